@@ -20,6 +20,11 @@ var (
 	NotEnoughGold               = myError.NewError(11, errors.New("钻石不足"))
 	UserDataLocked              = myError.NewError(12, errors.New("用户数据被锁定"))
 	NotEnoughScore              = myError.NewError(13, errors.New("积分不足"))
+	RouteNotFound               = myError.NewError(14, errors.New("路由不存在"))
+	ServerUnavailable           = myError.NewError(15, errors.New("服务器不可用"))
+	RequestTooFrequent          = myError.NewError(16, errors.New("请求过于频繁"))
+	RequestTimeout              = myError.NewError(17, errors.New("请求超时"))
+	ServerBusy                  = myError.NewError(18, errors.New("服务器繁忙"))
 	AccountOrPasswordError      = myError.NewError(101, errors.New("账号或密码错误"))
 	GetHallServersFail          = myError.NewError(102, errors.New("获取大厅服务器失败"))
 	AccountExist                = myError.NewError(103, errors.New("账号已存在"))
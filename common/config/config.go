@@ -31,6 +31,7 @@ type ServicesConf struct {
 type Domain struct {
 	Name        string `mapstructure:"name"`
 	LoadBalance bool   `mapstructure:"loadBalance"`
+	Balance     string `mapstructure:"balance"` //选择节点的策略 load weight hash
 }
 type JwtConf struct {
 	Secret string `mapstructure:"secret"`
@@ -68,6 +69,8 @@ type EtcdConf struct {
 	RWTimeout   int            `mapstructure:"rwTimeout"`
 	DialTimeout int            `mapstructure:"dialTimeout"`
 	Register    RegisterServer `mapstructure:"register"`
+	//servers.json和gameConfig.json在etcd中的key前缀 默认/config/
+	ConfigPrefix string `mapstructure:"configPrefix"`
 }
 type RegisterServer struct {
 	Addr    string `mapstructure:"addr"`
@@ -75,6 +78,7 @@ type RegisterServer struct {
 	Version string `mapstructure:"version"`
 	Weight  int    `mapstructure:"weight"`
 	Ttl     int64  `mapstructure:"ttl"` //租约时长
+	Id      string `mapstructure:"id"`
 }
 type GrpcConf struct {
 	Addr string `mapstructure:"addr"`
@@ -85,6 +85,14 @@ func (r *RedisManager) Close() {
 	}
 }
 
+// Cmd 返回当前使用的客户端 单机和集群都实现了redis.Cmdable
+func (r *RedisManager) Cmd() redis.Cmdable {
+	if r.ClusterCli != nil {
+		return r.ClusterCli
+	}
+	return r.Cli
+}
+
 // Set 封装set操作，-expire超时
 func (r *RedisManager) Set(ctx context.Context, key, value string, expire time.Duration) error {
 	if r.ClusterCli != nil {
@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"hash/crc32"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+)
+
+// Balance 选择节点的策略
+type Balance string
+
+const (
+	BalanceLoad   Balance = "load"   // 按权重折算后负载最低的
+	BalanceWeight Balance = "weight" // 按权重随机
+	BalanceHash   Balance = "hash"   // 一致性hash 同一个key尽量落在同一个节点
+)
+
+const hashReplicas = 100 // 每个权重对应的虚拟节点数
+
+func pick(balance Balance, servers []Server) (Server, bool) {
+	if len(servers) == 0 {
+		return Server{}, false
+	}
+	switch balance {
+	case BalanceWeight:
+		return pickWeight(servers), true
+	default:
+		return pickLoad(servers), true
+	}
+}
+
+// pickLoad 负载除以权重最小的 相同时随机一个 避免同时登录的用户都挤到同一个节点
+func pickLoad(servers []Server) Server {
+	var best []Server
+	var bestScore float64
+	for _, s := range servers {
+		score := float64(s.Load) / float64(weight(s))
+		if len(best) == 0 || score < bestScore {
+			best = []Server{s}
+			bestScore = score
+		} else if score == bestScore {
+			best = append(best, s)
+		}
+	}
+	return best[rand.IntN(len(best))]
+}
+
+func pickWeight(servers []Server) Server {
+	total := 0
+	for _, s := range servers {
+		total += weight(s)
+	}
+	n := rand.IntN(total)
+	for _, s := range servers {
+		n -= weight(s)
+		if n < 0 {
+			return s
+		}
+	}
+	return servers[len(servers)-1]
+}
+
+func weight(s Server) int {
+	if s.Weight <= 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+// hashRing 一致性hash环 节点增减时只有少部分key会换节点
+type hashRing struct {
+	hashes []uint32
+	nodes  map[uint32]Server
+}
+
+func newHashRing(servers []Server) *hashRing {
+	r := &hashRing{nodes: make(map[uint32]Server)}
+	for _, s := range servers {
+		for i := 0; i < hashReplicas*weight(s); i++ {
+			h := crc32.ChecksumIEEE([]byte(s.Addr + "#" + strconv.Itoa(i)))
+			if _, ok := r.nodes[h]; ok {
+				continue
+			}
+			r.nodes[h] = s
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+func (r *hashRing) get(key string) (Server, bool) {
+	if len(r.hashes) == 0 {
+		return Server{}, false
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]], true
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"sync"
 	"time"
 )
 
@@ -15,6 +16,7 @@ import (
 // 过了租约时间，etcd就会删除grpc服务信息
 // 实现心跳(完成续租)	，如果etcd没有 就重新注册
 type Register struct {
+	mu          sync.Mutex                              //保护info和leaseId 负载更新和重新注册在不同的协程
 	etcdCli     *clientv3.Client                        //etcd连接
 	leaseId     clientv3.LeaseID                        //租约id
 	DialTimeout int                                     //超时时间
@@ -42,6 +44,7 @@ func (r *Register) Register(conf config.EtcdConf) error {
 		Weight:  conf.Register.Weight,
 		Version: conf.Register.Version,
 		Ttl:     conf.Register.Ttl,
+		Id:      conf.Register.Id,
 	}
 	////建立etcd的连接
 	var err error
@@ -67,6 +70,8 @@ func (r *Register) Register(conf config.EtcdConf) error {
 
 // 注册：创建租约、心跳检测、绑定租约
 func (r *Register) register() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	//1. 创建租约
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(r.DialTimeout))
 	defer cancel()
@@ -139,6 +144,7 @@ func (r *Register) watcher() {
 				r.etcdCli.Close()
 			}
 			logs.Info("unregister etcd...")
+			return
 		case <-r.keepAliveCh: //情况二：续约；拿到心跳结果，进行续约
 			//logs.Info("%v", res)
 			//if res != nil {
@@ -156,6 +162,25 @@ func (r *Register) watcher() {
 	}
 }
 
+// UpdateLoad 更新注册信息中的负载 使用同一个租约 写入成功后才记录 失败时下次重试
+func (r *Register) UpdateLoad(load int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.info.Load == load {
+		return nil
+	}
+	info := r.info
+	info.Load = load
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(r.DialTimeout))
+	defer cancel()
+	data, _ := json.Marshal(info)
+	if _, err := r.etcdCli.Put(ctx, info.BuildRegisterKey(), string(data), clientv3.WithLease(r.leaseId)); err != nil {
+		return err
+	}
+	r.info.Load = load
+	return nil
+}
+
 func (r *Register) unregister() error {
 	//删除操作
 	_, err := r.etcdCli.Delete(context.Background(), r.info.BuildRegisterKey())
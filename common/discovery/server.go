@@ -13,6 +13,8 @@ type Server struct {
 	Weight  int    `json:"weight"` //权重
 	Version string `json:"version"`
 	Ttl     int64  `json:"ttl"`
+	Id      string `json:"id,omitempty"`   //服务器id 例如connector001
+	Load    int    `json:"load,omitempty"` //当前负载 例如connector的连接数
 }
 
 // BuildRegisterKey 构造注册所需的key,根据是否含有version判断，key是否要加version参数
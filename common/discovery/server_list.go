@@ -0,0 +1,210 @@
+package discovery
+
+import (
+	"common/config"
+	"common/logs"
+	"context"
+	"fmt"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"sync"
+	"time"
+)
+
+// ServerList 监听etcd中某一类服务的全部节点 例如gate需要知道所有connector的地址和负载
+type ServerList struct {
+	sync.RWMutex
+	conf    config.EtcdConf
+	etcdCli *clientv3.Client
+	prefix  string
+	servers map[string]Server // key -> server
+	rev     int64             // 已经同步到的revision 重新watch时从下一个开始
+	ring    *hashRing
+	closeCh chan struct{}
+}
+
+// NewServerList name为注册时的名字 带版本时为 name/version
+func NewServerList(conf config.EtcdConf, name string) (*ServerList, error) {
+	dialTimeout := conf.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 3
+	}
+	etcdCli, err := clientv3.New(clientv3.Config{
+		Endpoints:   conf.Addrs,
+		DialTimeout: time.Duration(dialTimeout) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := &ServerList{
+		conf:    conf,
+		etcdCli: etcdCli,
+		prefix:  fmt.Sprintf("/%s/", name),
+		servers: make(map[string]Server),
+		ring:    newHashRing(nil),
+		closeCh: make(chan struct{}),
+	}
+	if err := l.sync(); err != nil {
+		etcdCli.Close()
+		return nil, err
+	}
+	go l.watch()
+	return l, nil
+}
+
+// Servers 当前所有节点的拷贝
+func (l *ServerList) Servers() []Server {
+	l.RLock()
+	defer l.RUnlock()
+	list := make([]Server, 0, len(l.servers))
+	for _, v := range l.servers {
+		list = append(list, v)
+	}
+	return list
+}
+
+// Pick 按策略选择一个节点 key只在一致性hash时使用 例如uid
+func (l *ServerList) Pick(balance Balance, key string) (Server, bool) {
+	if balance == BalanceHash {
+		l.RLock()
+		defer l.RUnlock()
+		return l.ring.get(key)
+	}
+	return pick(balance, l.Servers())
+}
+
+// PickExcept 排除指定id的节点后按策略选择 例如connector下线时给客户端找一个其他的connector
+func (l *ServerList) PickExcept(balance Balance, id string) (Server, bool) {
+	servers := l.Servers()
+	candidates := make([]Server, 0, len(servers))
+	for _, v := range servers {
+		if v.Id != id {
+			candidates = append(candidates, v)
+		}
+	}
+	if balance == BalanceHash {
+		balance = BalanceLoad
+	}
+	return pick(balance, candidates)
+}
+
+func (l *ServerList) Close() {
+	close(l.closeCh)
+}
+
+func (l *ServerList) sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.rwTimeout())
+	defer cancel()
+	res, err := l.etcdCli.Get(ctx, l.prefix, clientv3.WithPrefix())
+	if err != nil {
+		logs.Error("server list get etcd failed, prefix=%s, err:%v", l.prefix, err)
+		return err
+	}
+	servers := make(map[string]Server, len(res.Kvs))
+	for _, v := range res.Kvs {
+		server, err := ParseValue(v.Value)
+		if err != nil {
+			logs.Error("server list parse etcd value failed, key=%s, err:%v", v.Key, err)
+			continue
+		}
+		servers[string(v.Key)] = server
+	}
+	l.Lock()
+	l.servers = servers
+	l.rev = res.Header.Revision
+	l.rebuild()
+	l.Unlock()
+	return nil
+}
+
+// newWatch 从已经同步到的revision之后开始watch 期间的变化不会丢
+func (l *ServerList) newWatch() clientv3.WatchChan {
+	l.RLock()
+	rev := l.rev
+	l.RUnlock()
+	return l.etcdCli.Watch(context.Background(), l.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+}
+
+func (l *ServerList) watch() {
+	watchCh := l.newWatch()
+	//watch可能丢事件 定时全量同步一次
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.closeCh:
+			l.closeEtcd()
+			return
+		case res, ok := <-watchCh:
+			if !ok || res.Canceled || res.Err() != nil {
+				//例如版本被压缩 全量同步一次后从新的revision重新watch
+				logs.Error("server list watch %s err:%v, rewatch", l.prefix, res.Err())
+				select {
+				case <-l.closeCh:
+					l.closeEtcd()
+					return
+				case <-time.After(time.Second):
+				}
+				if err := l.sync(); err != nil {
+					logs.Error("server list sync failed, err:%v", err)
+				}
+				watchCh = l.newWatch()
+				continue
+			}
+			l.update(res.Header.Revision, res.Events)
+		case <-ticker.C:
+			if err := l.sync(); err != nil {
+				logs.Error("server list sync failed, err:%v", err)
+			}
+		}
+	}
+}
+
+func (l *ServerList) closeEtcd() {
+	if err := l.etcdCli.Close(); err != nil {
+		logs.Error("server list close etcd err:%v", err)
+	}
+}
+
+func (l *ServerList) update(rev int64, events []*clientv3.Event) {
+	l.Lock()
+	defer l.Unlock()
+	l.rev = rev
+	changed := false
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		switch ev.Type {
+		case clientv3.EventTypePut:
+			server, err := ParseValue(ev.Kv.Value)
+			if err != nil {
+				logs.Error("server list parse etcd value failed, key=%s, err:%v", key, err)
+				continue
+			}
+			//只有负载变化时不需要重建hash环
+			if old, ok := l.servers[key]; !ok || old.Addr != server.Addr || old.Weight != server.Weight {
+				changed = true
+			}
+			l.servers[key] = server
+		case clientv3.EventTypeDelete:
+			delete(l.servers, key)
+			changed = true
+		}
+	}
+	if changed {
+		l.rebuild()
+	}
+}
+
+func (l *ServerList) rebuild() {
+	list := make([]Server, 0, len(l.servers))
+	for _, v := range l.servers {
+		list = append(list, v)
+	}
+	l.ring = newHashRing(list)
+}
+
+func (l *ServerList) rwTimeout() time.Duration {
+	if l.conf.RWTimeout <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(l.conf.RWTimeout) * time.Second
+}
@@ -3,8 +3,10 @@ package app
 import (
 	"common/config"
 	"common/logs"
-	//"connector/route"
+	"connector/route"
 	"context"
+	"core/dao"
+	"core/repo"
 	"framework/connector"
 	"os"
 	"os/signal"
@@ -16,13 +18,17 @@ import (
 func Run(ctx context.Context, serverId string) error {
 	//1.做一个日志库 info error fatal debug
 	logs.InitLog(config.Conf.AppName)
-	exit := func() {}
+	conn := connector.Default()
+	manager := repo.New()
+	conn.RegisterHandler(route.Register(manager))
+	conn.SetUserRegistry(dao.NewOnlineDao(manager))
+	if len(config.Conf.Etcd.Addrs) > 0 {
+		conn.EnableDiscovery(config.Conf.Etcd)
+	}
+	exit := conn.Close
+	drain := func() { conn.Drain(0) }
 	go func() {
-		c := connector.Default()
-		exit = c.Close
-		//manager := repo.New()
-		//c.RegisterHandler(route.Register(manager))
-		c.Run(serverId)
+		conn.Run(serverId)
 	}()
 	stop := func() {
 		//other
@@ -41,7 +47,13 @@ func Run(ctx context.Context, serverId string) error {
 			return nil
 		case s := <-c:
 			switch s {
-			case syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT:
+			case syscall.SIGTERM:
+				//滚动发布 先让客户端迁移到其他connector
+				drain()
+				stop()
+				logs.Info("connector app drained and quit")
+				return nil
+			case syscall.SIGQUIT, syscall.SIGINT:
 				stop()
 				logs.Info("connector app quit")
 				return nil
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"common/biz"
+	"common/logs"
+	"core/repo"
+	"framework/game"
+	"framework/myError"
+	"framework/net"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type EntryHandler struct {
+	repo *repo.Manager
+}
+
+// ConfigReq 没有参数 使用proto的空消息 协商了protobuf的客户端也可以请求
+type ConfigReq = emptypb.Empty
+
+// Config 获取前端需要的游戏配置 返回google.protobuf.Struct json客户端收到的是普通的json对象
+func (h *EntryHandler) Config(session *net.Session, req *ConfigReq) (any, *myError.Error) {
+	front, err := game.Conf.GameConfig().FrontStruct()
+	if err != nil {
+		logs.Error("encode front game config err:%v", err)
+		return nil, biz.Fail
+	}
+	return front, nil
+}
+
+func NewEntryHandler(r *repo.Manager) *EntryHandler {
+	return &EntryHandler{
+		repo: r,
+	}
+}
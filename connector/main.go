@@ -10,6 +10,8 @@ import (
 	"github.com/spf13/cobra"
 	"log"
 	"os"
+	"path"
+	"time"
 )
 
 var rootCmd = &cobra.Command{
@@ -17,22 +19,42 @@ var rootCmd = &cobra.Command{
 	Short: "connector 管理连接，session以及路由请求",
 	Long:  `connector 管理连接，session以及路由请求`,
 	Run: func(cmd *cobra.Command, args []string) {
+		run()
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
 	},
 }
 
+// publishCmd 把本地的servers.json和gameConfig.json校验后发布到etcd 使用application.yml中的etcd配置
+//
+//	connector publish --config application.yml --gameDir ../config
+var publishCmd = &cobra.Command{
+	Use:   "publish [servers.json|gameConfig.json]...",
+	Short: "发布本地的配置文件到etcd",
+	Long:  `校验gameDir中的配置文件并发布到etcd 不指定文件时发布servers.json和gameConfig.json`,
+	//校验或者发布失败时不需要输出用法
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return publish(args)
+	},
+}
+
 var (
-	configFile    string
-	gameConfigDir string
-	serverId      string
+	configFile     string
+	gameConfigDir  string
+	serverId       string
+	configSource   string
+	publishVersion int64
 )
 
 func init() {
-	rootCmd.Flags().StringVar(&configFile, "config", "application.yml", "app config yml file")
-	rootCmd.Flags().StringVar(&gameConfigDir, "gameDir", "../config", "game config dir")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "application.yml", "app config yml file")
+	rootCmd.PersistentFlags().StringVar(&gameConfigDir, "gameDir", "../config", "game config dir")
 	rootCmd.Flags().StringVar(&serverId, "serverId", "", "app server id， required")
+	rootCmd.Flags().StringVar(&configSource, "configSource", "file", "load game config from file or etcd")
 	_ = rootCmd.MarkFlagRequired("serverId")
+	publishCmd.Flags().Int64Var(&publishVersion, "version", 0, "version to publish, must be greater than the current one, 0 means current+1")
+	rootCmd.AddCommand(publishCmd)
 }
 
 //var configFile = flag.String("config", "application.yml", "config file")
@@ -46,8 +68,20 @@ func main() {
 		log.Println(err)
 		os.Exit(1)
 	}
+}
+
+func run() {
 	config.InitConfig(configFile)
-	game.InitConfig(gameConfigDir)
+	var opts []game.Option
+	switch configSource {
+	case "file":
+	case "etcd":
+		opts = append(opts, game.WithEtcd(config.Conf.Etcd))
+	default:
+		log.Printf("unknown configSource %s", configSource)
+		os.Exit(1)
+	}
+	game.InitConfig(gameConfigDir, opts...)
 	go func() {
 		err := metrics.Serve(fmt.Sprintf("0.0.0.0:#{config.Conf.MetricPort"))
 		if err != nil {
@@ -61,3 +95,40 @@ func main() {
 		os.Exit(-1)
 	}
 }
+
+// publish 先校验所有文件 都通过后再逐个发布
+func publish(names []string) error {
+	if len(names) == 0 {
+		names = []string{"servers.json", "gameConfig.json"}
+	}
+	if len(names) > 1 && publishVersion != 0 {
+		return fmt.Errorf("--version can only be used when publishing one file")
+	}
+	config.InitConfig(configFile)
+	docs := make([][]byte, len(names))
+	for i, name := range names {
+		data, err := os.ReadFile(path.Join(gameConfigDir, name))
+		if err != nil {
+			return err
+		}
+		if err := game.ValidateConfig(name, data); err != nil {
+			return fmt.Errorf("%s is invalid: %w", name, err)
+		}
+		docs[i] = data
+	}
+	cli, err := game.NewEtcdClient(config.Conf.Etcd)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	for i, name := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		version, err := game.PublishConfig(ctx, cli, config.Conf.Etcd, name, docs[i], publishVersion)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("publish %s err: %w", name, err)
+		}
+		log.Printf("published %s version %d to %s", name, version, game.EtcdConfigKey(config.Conf.Etcd, name))
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package route
+
+import (
+	"connector/handler"
+	"core/repo"
+	"framework/net"
+)
+
+// Register 注册connector处理的路由
+func Register(r *repo.Manager) net.LogicHandler {
+	handlers := make(net.LogicHandler)
+	entryHandler := handler.NewEntryHandler(r)
+	handlers["connector.entryHandler.config"] = net.Handle(entryHandler.Config)
+	return handlers
+}
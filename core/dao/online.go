@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"context"
+	"core/repo"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"strings"
+	"time"
+)
+
+const OnlineRedisKey = "Online"
+
+// 只有记录的还是当前连接时才删除 防止把新登录的记录删掉
+var unbindScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// 记录的还是当前连接时续期
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// OnlineDao 记录用户当前所在的connector和连接 uid -> connectorId|cid
+type OnlineDao struct {
+	repo *repo.Manager
+}
+
+func (d *OnlineDao) key(uid string) string {
+	return Prefix + ":" + OnlineRedisKey + ":" + uid
+}
+
+// Bind 记录用户新的连接 ttl后过期 返回之前的connector和连接 没有时为空
+func (d *OnlineDao) Bind(ctx context.Context, uid, connectorId, cid string, ttl time.Duration) (string, string, error) {
+	old, err := d.repo.Redis.Cmd().SetArgs(ctx, d.key(uid), connectorId+"|"+cid, redis.SetArgs{Get: true, TTL: ttl}).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	oldConnectorId, oldCid, _ := strings.Cut(old, "|")
+	return oldConnectorId, oldCid, nil
+}
+
+// Unbind 连接断开时删除记录
+func (d *OnlineDao) Unbind(ctx context.Context, uid, connectorId, cid string) error {
+	return unbindScript.Run(ctx, d.repo.Redis.Cmd(), []string{d.key(uid)}, connectorId+"|"+cid).Err()
+}
+
+// Refresh 给connector上的连接续期 users: uid -> cid 使用pipeline一次发送
+func (d *OnlineDao) Refresh(ctx context.Context, connectorId string, users map[string]string, ttl time.Duration) error {
+	pipe := d.repo.Redis.Cmd().Pipeline()
+	for uid, cid := range users {
+		refreshScript.Eval(ctx, pipe, []string{d.key(uid)}, connectorId+"|"+cid, ttl.Milliseconds())
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func NewOnlineDao(m *repo.Manager) *OnlineDao {
+	return &OnlineDao{
+		repo: m,
+	}
+}
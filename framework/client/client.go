@@ -0,0 +1,424 @@
+package client
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"framework/myError"
+	"framework/net"
+	"framework/protocol"
+	"framework/serializer"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	clientType            = "go-client"
+	defaultVersion        = "1.0.0"
+	defaultDialTimeout    = 10 * time.Second
+	defaultRequestTimeout = 5 * time.Second
+)
+
+var (
+	ErrClosed           = errors.New("client closed")
+	ErrRequestTimeout   = errors.New("request timeout")
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+	ErrKicked           = errors.New("kicked by server")
+)
+
+// PushHandler 处理服务端的推送 在读协程中执行 不要阻塞
+type PushHandler func(data []byte)
+
+// Client 连接connector的客户端 机器人 压测和hall game的集成测试使用
+// 创建后按需修改导出的字段 再调用Connect
+//
+//	c := client.NewClient("127.0.0.1:12000")
+//	c.Serializer = serializer.Protobuf
+//	if err := c.Connect(); err != nil { ... }
+//	uid, err := c.Auth(token)
+//	err = c.Request("hall.userHandler.info", &pb.InfoReq{}, &res)
+type Client struct {
+	sync.Mutex
+	Addr           string
+	Transport      string //ws tcp 默认ws
+	Secure         bool   //使用wss或者tls
+	TLSConfig      *tls.Config
+	Serializer     string //期望的序列化方式 以握手回复的为准
+	Version        string //客户端版本 低于connector要求的版本会握手失败
+	Platform       string
+	AuthRoute      string
+	Token          string //websocket升级时带上token直接认证 不用再调用Auth tcp不支持
+	ResumeToken    string //上一个连接握手下发的恢复凭证 用来在宽限期内找回session 只支持websocket
+	DialTimeout    time.Duration
+	RequestTimeout time.Duration
+	conn           conn
+	serializer     serializer.Serializer
+	sys            protocol.HandshakeSys
+	nextId         uint
+	pending        map[uint]chan *protocol.Message
+	handlers       map[string][]PushHandler
+	onKick         func(protocol.KickBody)
+	onClose        func(error)
+	lastRecv       atomic.Int64
+	closeChan      chan struct{}
+	closeOnce      sync.Once
+	err            error
+}
+
+func NewClient(addr string) *Client {
+	return &Client{
+		Addr:           addr,
+		Transport:      net.TransportWs,
+		Serializer:     serializer.Json,
+		Version:        defaultVersion,
+		AuthRoute:      net.DefaultAuthRoute,
+		DialTimeout:    defaultDialTimeout,
+		RequestTimeout: defaultRequestTimeout,
+		serializer:     serializer.Default(),
+		pending:        make(map[uint]chan *protocol.Message),
+		handlers:       make(map[string][]PushHandler),
+		closeChan:      make(chan struct{}),
+	}
+}
+
+// Connect 连接并完成握手 之后开始按握手下发的间隔发送心跳
+func (c *Client) Connect() error {
+	conn, err := dial(c.Transport, c.Addr, c.Secure, c.TLSConfig, c.DialTimeout, c.query())
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return err
+	}
+	c.lastRecv.Store(time.Now().UnixNano())
+	go c.readLoop()
+	go c.heartbeatLoop()
+	return nil
+}
+
+// query websocket升级请求的参数
+func (c *Client) query() url.Values {
+	query := url.Values{}
+	if c.Token != "" {
+		query.Set("token", c.Token)
+	}
+	if c.ResumeToken != "" {
+		query.Set("resume", c.ResumeToken)
+	}
+	return query
+}
+
+// handshake 发送握手包 等待回复后使用协商的序列化方式和路由字典 最后回复握手确认
+func (c *Client) handshake() error {
+	body, _ := json.Marshal(protocol.HandshakeBody{
+		Sys: protocol.HandshakeClient{
+			Type:       clientType,
+			Version:    c.Version,
+			Platform:   c.Platform,
+			Serializer: c.Serializer,
+		},
+	})
+	if err := c.writePacket(protocol.Handshake, body); err != nil {
+		return err
+	}
+	for {
+		buf, err := c.conn.ReadPacket()
+		if err != nil {
+			return err
+		}
+		packet, err := protocol.Decode(buf)
+		if err != nil {
+			return err
+		}
+		switch packet.Type {
+		case protocol.Kick:
+			return kickError(packet.Body)
+		case protocol.Handshake:
+		default:
+			continue
+		}
+		var res protocol.HandshakeResponse
+		if err := json.Unmarshal(packet.Body, &res); err != nil {
+			return err
+		}
+		if res.Code != protocol.HandshakeOK {
+			return fmt.Errorf("handshake failed, code=%d", res.Code)
+		}
+		c.sys = res.Sys
+		if s, ok := serializer.Get(res.Sys.Serializer); ok {
+			c.serializer = s
+		}
+		if err := protocol.AddDictionary(res.Sys.Dict); err != nil {
+			return err
+		}
+		return c.writePacket(protocol.HandshakeAck, nil)
+	}
+}
+
+// Sys 握手回复的系统信息 例如心跳间隔和恢复凭证
+func (c *Client) Sys() protocol.HandshakeSys {
+	return c.sys
+}
+
+// Auth 用gate登录时下发的token认证 返回绑定的uid
+func (c *Client) Auth(token string) (string, error) {
+	var res net.AuthRes
+	if err := c.Request(c.AuthRoute, &net.AuthReq{Token: token}, &res); err != nil {
+		return "", err
+	}
+	return res.Uid, nil
+}
+
+// Request 发送请求并等待回复 resp为nil时忽略回复的内容
+// 服务端返回错误时err是*myError.Error 可以取到错误码
+func (c *Client) Request(route string, req any, resp any) error {
+	data, err := c.marshal(req)
+	if err != nil {
+		return err
+	}
+	ch := make(chan *protocol.Message, 1)
+	c.Lock()
+	if c.pending == nil {
+		err := c.err
+		c.Unlock()
+		return err
+	}
+	c.nextId++
+	id := c.nextId
+	c.pending[id] = ch
+	c.Unlock()
+	defer func() {
+		c.Lock()
+		delete(c.pending, id)
+		c.Unlock()
+	}()
+	if err := c.writeMessage(&protocol.Message{
+		Type:  protocol.Request,
+		ID:    id,
+		Route: route,
+		Data:  data,
+	}); err != nil {
+		return err
+	}
+	timer := time.NewTimer(c.RequestTimeout)
+	defer timer.Stop()
+	select {
+	case msg := <-ch:
+		if msg.Error {
+			return c.decodeError(msg.Data)
+		}
+		if resp == nil || len(msg.Data) == 0 {
+			return nil
+		}
+		return c.serializer.Unmarshal(msg.Data, resp)
+	case <-timer.C:
+		return ErrRequestTimeout
+	case <-c.closeChan:
+		return c.closedErr()
+	}
+}
+
+// Notify 发送通知 服务端不回复
+func (c *Client) Notify(route string, req any) error {
+	data, err := c.marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.writeMessage(&protocol.Message{
+		Type:  protocol.Notify,
+		Route: route,
+		Data:  data,
+	})
+}
+
+// On 订阅某个路由的推送 同一个路由可以订阅多次 按订阅的顺序执行
+// 推送的内容用Decode解码
+func (c *Client) On(route string, fn PushHandler) {
+	c.Lock()
+	defer c.Unlock()
+	c.handlers[route] = append(c.handlers[route], fn)
+}
+
+// OnKick 被踢下线时回调 之后连接会被关闭
+func (c *Client) OnKick(fn func(body protocol.KickBody)) {
+	c.Lock()
+	defer c.Unlock()
+	c.onKick = fn
+}
+
+// OnClose 连接关闭时回调 主动调用Close时err为ErrClosed
+func (c *Client) OnClose(fn func(err error)) {
+	c.Lock()
+	defer c.Unlock()
+	c.onClose = fn
+}
+
+// Decode 按协商的序列化方式解码推送的内容
+func (c *Client) Decode(data []byte, v any) error {
+	return c.serializer.Unmarshal(data, v)
+}
+
+// Done 连接关闭后返回的channel会被关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.closeChan
+}
+
+func (c *Client) Close() error {
+	c.closeWith(ErrClosed)
+	return nil
+}
+
+func (c *Client) readLoop() {
+	for {
+		buf, err := c.conn.ReadPacket()
+		if err != nil {
+			c.closeWith(err)
+			return
+		}
+		c.lastRecv.Store(time.Now().UnixNano())
+		packet, err := protocol.Decode(buf)
+		if err != nil {
+			c.closeWith(err)
+			return
+		}
+		switch packet.Type {
+		case protocol.Data:
+			msg, err := protocol.MessageDecode(packet.Body)
+			if err != nil {
+				c.closeWith(err)
+				return
+			}
+			c.dispatch(msg)
+		case protocol.Kick:
+			var body protocol.KickBody
+			_ = json.Unmarshal(packet.Body, &body)
+			c.Lock()
+			onKick := c.onKick
+			c.Unlock()
+			if onKick != nil {
+				onKick(body)
+			}
+			c.closeWith(kickError(packet.Body))
+			return
+		}
+	}
+}
+
+// dispatch 回复交给等待的请求 推送交给订阅的handler
+func (c *Client) dispatch(msg *protocol.Message) {
+	switch msg.Type {
+	case protocol.Response:
+		c.Lock()
+		ch, ok := c.pending[msg.ID]
+		c.Unlock()
+		if ok {
+			ch <- msg
+		}
+	case protocol.Push:
+		c.Lock()
+		handlers := c.handlers[msg.Route]
+		c.Unlock()
+		for _, fn := range handlers {
+			fn(msg.Data)
+		}
+	}
+}
+
+// heartbeatLoop 按握手下发的间隔发送心跳 两个间隔内没有收到任何包认为连接已经断开
+func (c *Client) heartbeatLoop() {
+	interval := time.Duration(c.sys.Heartbeat) * time.Second
+	if interval <= 0 {
+		interval = protocol.DefaultHeartbeatSeconds * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeChan:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastRecv.Load())) > 2*interval {
+				c.closeWith(ErrHeartbeatTimeout)
+				return
+			}
+			if err := c.writePacket(protocol.Heartbeat, nil); err != nil {
+				c.closeWith(err)
+				return
+			}
+		}
+	}
+}
+
+// closeWith 关闭连接 等待中的请求都返回关闭的原因
+func (c *Client) closeWith(err error) {
+	c.closeOnce.Do(func() {
+		c.Lock()
+		c.err = err
+		c.pending = nil
+		onClose := c.onClose
+		c.Unlock()
+		close(c.closeChan)
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		if onClose != nil {
+			onClose(err)
+		}
+	})
+}
+
+func (c *Client) closedErr() error {
+	c.Lock()
+	defer c.Unlock()
+	if c.err == nil {
+		return ErrClosed
+	}
+	return c.err
+}
+
+func (c *Client) marshal(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return c.serializer.Marshal(v)
+}
+
+func (c *Client) decodeError(data []byte) error {
+	var body net.ErrorBody
+	if err := c.serializer.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	return myError.NewError(int(body.Code), errors.New(body.Msg))
+}
+
+func (c *Client) writeMessage(msg *protocol.Message) error {
+	body, err := protocol.MessageEncode(msg)
+	if err != nil {
+		return err
+	}
+	return c.writePacket(protocol.Data, body)
+}
+
+func (c *Client) writePacket(typ protocol.PackageType, body []byte) error {
+	select {
+	case <-c.closeChan:
+		return c.closedErr()
+	default:
+	}
+	buf, err := protocol.Encode(typ, body)
+	if err != nil {
+		return err
+	}
+	return c.conn.WritePacket(buf)
+}
+
+func kickError(data []byte) error {
+	var body protocol.KickBody
+	_ = json.Unmarshal(data, &body)
+	return fmt.Errorf("%w: code=%d reason=%s", ErrKicked, body.Code, body.Reason)
+}
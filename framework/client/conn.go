@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"framework/net"
+	"framework/protocol"
+	"io"
+	gonet "net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const writeWait = 10 * time.Second
+
+// conn 屏蔽websocket和tcp的差异 读写的都是完整的包
+type conn interface {
+	ReadPacket() ([]byte, error)
+	WritePacket(buf []byte) error
+	Close() error
+}
+
+// dial 按传输方式连接connector query用于websocket升级时带上token或resume
+func dial(transport, addr string, secure bool, tlsConf *tls.Config, timeout time.Duration, query url.Values) (conn, error) {
+	switch transport {
+	case "", net.TransportWs:
+		scheme := "ws"
+		if secure {
+			scheme = "wss"
+		}
+		u := url.URL{Scheme: scheme, Host: addr, Path: "/", RawQuery: query.Encode()}
+		dialer := websocket.Dialer{
+			HandshakeTimeout: timeout,
+			TLSClientConfig:  tlsConf,
+		}
+		c, _, err := dialer.Dial(u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return &wsConn{conn: c}, nil
+	case net.TransportTcp:
+		d := &gonet.Dialer{Timeout: timeout}
+		var c gonet.Conn
+		var err error
+		if secure {
+			c, err = tls.DialWithDialer(d, "tcp", addr, tlsConf)
+		} else {
+			c, err = d.Dial("tcp", addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &tcpConn{conn: c, reader: bufio.NewReader(c)}, nil
+	}
+	return nil, fmt.Errorf("unsupported transport: %s", transport)
+}
+
+type wsConn struct {
+	sync.Mutex
+	conn *websocket.Conn
+}
+
+func (c *wsConn) ReadPacket() ([]byte, error) {
+	for {
+		typ, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if typ == websocket.BinaryMessage {
+			return data, nil
+		}
+	}
+}
+
+// WritePacket websocket同一时间只能有一个写
+func (c *wsConn) WritePacket(buf []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(websocket.BinaryMessage, buf)
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+type tcpConn struct {
+	sync.Mutex
+	conn   gonet.Conn
+	reader *bufio.Reader
+}
+
+// ReadPacket 先读包头 再按长度读包体 返回完整的包
+func (c *tcpConn) ReadPacket() ([]byte, error) {
+	header := make([]byte, protocol.HeaderLen)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return nil, err
+	}
+	length := protocol.BytesToInt(header[1:])
+	buf := make([]byte, protocol.HeaderLen+length)
+	copy(buf, header)
+	if _, err := io.ReadFull(c.reader, buf[protocol.HeaderLen:]); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (c *tcpConn) WritePacket(buf []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
@@ -1,43 +1,66 @@
 package connector
 
 import (
+	"common/config"
+	"common/discovery"
 	"common/logs"
 	"fmt"
 	"framework/game"
 	"framework/net"
+	"framework/remote"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Connector struct {
-	isRunning bool
-	wsManager *net.Manager
-	//handlers  net.LogicHandler
-	//remoteCli remote.Client
+	isRunning  atomic.Bool
+	wsManager  *net.Manager
+	handlers   net.LogicHandler
+	remoteCli  remote.Client
+	registry   net.UserRegistry
+	draining   atomic.Bool
+	drainHooks []func()
+	//服务发现
+	etcdConf       *config.EtcdConf
+	registerCli    *discovery.Register
+	stopReport     chan struct{}
+	deregisterOnce sync.Once
 }
 
 func Default() *Connector {
 	return &Connector{
-		//handlers: make(net.LogicHandler),
+		handlers: make(net.LogicHandler),
 	}
 }
 
 func (c *Connector) Run(serverId string) {
 
-	if !c.isRunning {
+	if !c.isRunning.Load() {
 		//启动websocket和nats
 		c.wsManager = net.NewManager()
-		//c.wsManager.ConnectorHandlers = c.handlers
-		////启动nats nats server不会存储消息
-		//c.remoteCli = remote.NewNatsClient(serverId, c.wsManager.RemoteReadChan)
-		//c.remoteCli.Run()
-		//c.wsManager.RemoteCli = c.remoteCli
+		c.wsManager.ConnectorHandlers = c.handlers
+		c.wsManager.Registry = c.registry
+		//启动nats nats server不会存储消息
+		c.remoteCli = remote.NewClient(serverId, c.wsManager.RemoteReadChan)
+		if err := c.remoteCli.Run(); err != nil {
+			logs.Fatal("connector run remote client err:%v", err)
+		}
+		c.wsManager.RemoteCli = c.remoteCli
 		c.Serve(serverId)
 	}
 }
 
 func (c *Connector) Close() {
-	if c.isRunning {
+	if c.isRunning.Load() {
 		//关闭websocket和nats
+		c.deregister()
 		c.wsManager.Close()
+		if c.remoteCli != nil {
+			if err := c.remoteCli.Close(); err != nil {
+				logs.Error("connector close remote client err:%v", err)
+			}
+		}
 	}
 }
 
@@ -50,11 +73,48 @@ func (c *Connector) Serve(serverId string) {
 	if connectorConfig == nil {
 		logs.Fatal("no connector config found")
 	}
+	c.wsManager.HeartTime = connectorConfig.HeartTime
+	c.wsManager.AuthTimeout = time.Duration(connectorConfig.AuthTimeout) * time.Second
+	c.wsManager.WriteQueue = net.WriteQueueConfig{
+		Size:        connectorConfig.WriteQueueSize,
+		Policy:      net.WritePolicy(connectorConfig.WritePolicy),
+		SendTimeout: time.Duration(connectorConfig.SendTimeout) * time.Millisecond,
+	}
+	c.wsManager.MaxMessageSize = connectorConfig.MaxMessageSize
+	c.wsManager.RateLimit = connectorConfig.RateLimit
+	c.wsManager.CertFile = connectorConfig.CertFile
+	c.wsManager.KeyFile = connectorConfig.KeyFile
+	c.wsManager.AllowOrigins = connectorConfig.AllowOrigins
+	c.wsManager.EnableCompression = connectorConfig.Compression
+	c.wsManager.Serializers = connectorConfig.Serializers
+	c.wsManager.ResumeGrace = time.Duration(connectorConfig.ResumeGrace) * time.Second
+	c.wsManager.ResumeBuffer = connectorConfig.ResumeBuffer
+	c.wsManager.RPCTimeout = time.Duration(connectorConfig.RPCTimeOut) * time.Second
+	c.wsManager.HandleTimeout = time.Duration(connectorConfig.HandleTimeOut) * time.Second
+	c.wsManager.MaxRunRoutineNum = connectorConfig.MaxRunRoutineNum
 	addr := fmt.Sprintf("%s:%d", connectorConfig.Host, connectorConfig.ClientPort)
-	c.isRunning = true
+	c.wsManager.Transport = connectorConfig.Transport
+	tcpPort := connectorConfig.TcpPort
+	if tcpPort == 0 && connectorConfig.Transport == net.TransportTcp {
+		tcpPort = connectorConfig.ClientPort
+	}
+	if tcpPort == 0 && connectorConfig.Transport == net.TransportBoth {
+		logs.Fatal("connector %s transport both need tcpPort", serverId)
+	}
+	c.wsManager.TcpAddr = fmt.Sprintf("%s:%d", connectorConfig.Host, tcpPort)
+	loadRouteDict()
+	c.serveAdmin(connectorConfig)
+	c.register(connectorConfig)
+	c.watchGameConfig()
+	c.isRunning.Store(true)
 	c.wsManager.Run(addr)
 }
 
-//func (c *Connector) RegisterHandler(handlers net.LogicHandler) {
-//	c.handlers = handlers
-//}
+func (c *Connector) RegisterHandler(handlers net.LogicHandler) {
+	c.handlers = handlers
+}
+
+// SetUserRegistry 设置共享的在线用户注册表 用于跨connector的重复登录检测
+func (c *Connector) SetUserRegistry(registry net.UserRegistry) {
+	c.registry = registry
+}
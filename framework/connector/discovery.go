@@ -0,0 +1,74 @@
+package connector
+
+import (
+	"common/config"
+	"common/discovery"
+	"common/logs"
+	"fmt"
+	"framework/game"
+	"time"
+)
+
+const (
+	defaultRegisterTtl = 10
+	loadReportInterval = 5 * time.Second
+)
+
+// EnableDiscovery 把connector的客户端地址和连接数注册到etcd gate按负载选择connector
+func (c *Connector) EnableDiscovery(conf config.EtcdConf) {
+	c.etcdConf = &conf
+}
+
+func (c *Connector) register(conf *game.ConnectorConfig) {
+	if c.etcdConf == nil {
+		return
+	}
+	host := conf.ClientHost
+	if host == "" {
+		host = conf.Host
+	}
+	etcdConf := *c.etcdConf
+	etcdConf.Register = config.RegisterServer{
+		Name:   conf.ServerType,
+		Addr:   fmt.Sprintf("%s:%d", host, conf.ClientPort),
+		Weight: conf.Weight,
+		Ttl:    defaultRegisterTtl,
+		Id:     conf.ID,
+	}
+	r := discovery.NewRegister()
+	if err := r.Register(etcdConf); err != nil {
+		logs.Error("connector %s register etcd err:%v", conf.ID, err)
+		return
+	}
+	c.registerCli = r
+	c.stopReport = make(chan struct{})
+	//下线时先注销 gate不再分配新的客户端
+	c.OnDrain(c.deregister)
+	go c.reportLoad()
+}
+
+// reportLoad 定时更新连接数
+func (c *Connector) reportLoad() {
+	ticker := time.NewTicker(loadReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopReport:
+			return
+		case <-ticker.C:
+			if err := c.registerCli.UpdateLoad(c.wsManager.ClientCount()); err != nil {
+				logs.Error("connector update load err:%v", err)
+			}
+		}
+	}
+}
+
+func (c *Connector) deregister() {
+	if c.registerCli == nil {
+		return
+	}
+	c.deregisterOnce.Do(func() {
+		close(c.stopReport)
+		c.registerCli.Close()
+	})
+}
@@ -0,0 +1,141 @@
+package connector
+
+import (
+	"common/discovery"
+	"common/logs"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"framework/game"
+	"framework/protocol"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultDrainTimeout = 30 * time.Second
+	defaultAdminHost    = "127.0.0.1"
+)
+
+// OnDrain 下线时停止接受新连接之后执行 例如从服务发现中注销 避免gate继续分配新的客户端
+func (c *Connector) OnDrain(fn func()) {
+	c.drainHooks = append(c.drainHooks, fn)
+}
+
+// Drain 优雅下线 滚动发布时使用 timeout<=0时使用配置的drainTimeout
+// 不再接受新连接 通知客户端重连到其他connector 等待处理中的请求完成后关闭连接
+func (c *Connector) Drain(timeout time.Duration) {
+	if !c.isRunning.Load() || c.draining.Swap(true) {
+		return
+	}
+	conf := game.Conf.GetConnector(c.wsManager.ServerId)
+	if timeout <= 0 && conf != nil && conf.DrainTimeout > 0 {
+		timeout = time.Duration(conf.DrainTimeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+	//先拒绝新连接 再执行注销等操作 避免注销之前gate分配过来的客户端连进来
+	c.wsManager.StopAccepting()
+	for _, fn := range c.drainHooks {
+		fn()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	c.wsManager.Drain(ctx, c.reconnectTarget())
+}
+
+// reconnectTarget 从etcd中选择负载最低的其他connector 已经下线的connector会先注销 不会被选中
+// 没有启用服务发现或者没有其他connector时Host为空 让客户端回到gate重新获取
+func (c *Connector) reconnectTarget() protocol.ReconnectBody {
+	target := protocol.ReconnectBody{Reason: "server maintenance"}
+	conf := game.Conf.GetConnector(c.wsManager.ServerId)
+	if c.etcdConf == nil || conf == nil {
+		return target
+	}
+	list, err := discovery.NewServerList(*c.etcdConf, conf.ServerType)
+	if err != nil {
+		logs.Error("connector %s get connectors from etcd err:%v", conf.ID, err)
+		return target
+	}
+	defer list.Close()
+	server, ok := list.PickExcept(discovery.BalanceLoad, conf.ID)
+	if !ok {
+		return target
+	}
+	host, port, err := net.SplitHostPort(server.Addr)
+	if err != nil {
+		logs.Error("connector %s parse addr %s err:%v", server.Id, server.Addr, err)
+		return target
+	}
+	target.Host = host
+	target.Port, _ = strconv.Atoi(port)
+	if v := game.Conf.GetConnector(server.Id); v != nil {
+		target.Secure = v.CertFile != "" && v.KeyFile != ""
+	}
+	return target
+}
+
+// serveAdmin 管理接口 只在配置了adminPort时启动 默认只监听127.0.0.1 配置了adminToken时需要带上token
+//
+//	POST /drain?timeout=30 触发下线 timeout单位秒
+//	GET /stats 每个连接写队列的统计 用来排查处理不过来的客户端
+func (c *Connector) serveAdmin(conf *game.ConnectorConfig) {
+	if conf.AdminPort <= 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/drain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		seconds, _ := strconv.Atoi(r.URL.Query().Get("timeout"))
+		go c.Drain(time.Duration(seconds) * time.Second)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]any{"serverId": conf.ID, "draining": true})
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		stats := c.wsManager.ConnStats()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"serverId": conf.ID,
+			"draining": c.wsManager.Draining(),
+			"clients":  len(stats),
+			"conns":    stats,
+		})
+	})
+	host := conf.AdminHost
+	if host == "" {
+		host = defaultAdminHost
+	}
+	addr := fmt.Sprintf("%s:%d", host, conf.AdminPort)
+	go func() {
+		if err := http.ListenAndServe(addr, adminAuth(conf.AdminToken, mux)); err != nil {
+			logs.Error("connector admin listen serve err:%v", err)
+		}
+	}()
+}
+
+// adminAuth 校验请求头中的token token为空时不校验
+func adminAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,26 @@
+package connector
+
+import (
+	"common/logs"
+	"framework/game"
+	"framework/protocol"
+)
+
+// watchGameConfig gameConfig中下发给前端的配置变化后 推送给本connector上的所有连接
+// backend的项变化不推送
+func (c *Connector) watchGameConfig() {
+	game.Conf.OnChange(game.GameConfigKey, func(old, new any) {
+		oldConf, _ := old.(game.GameConfig)
+		newConf, _ := new.(game.GameConfig)
+		if !game.FrontChanged(oldConf, newConf) {
+			return
+		}
+		body, err := newConf.FrontStruct()
+		if err != nil {
+			logs.Error("encode front game config err:%v", err)
+			return
+		}
+		failed := c.wsManager.PushToAll(protocol.GameConfigRoute, body)
+		logs.Info("connector %s push front game config, failed=%d", c.wsManager.ServerId, len(failed))
+	})
+}
@@ -0,0 +1,27 @@
+package connector
+
+import (
+	"common/logs"
+	"errors"
+	"framework/game"
+	"framework/protocol"
+)
+
+// routeDictKey gameConfig中的路由压缩字典 路由 -> 压缩码 握手时下发给客户端
+const routeDictKey = "routeDict"
+
+// loadRouteDict 启动时加载路由压缩字典 没有配置时不压缩
+// 已经握手的客户端使用旧的字典 修改后需要重启connector
+func loadRouteDict() {
+	var dict map[string]uint16
+	if err := game.Conf.GetStruct(routeDictKey, &dict); err != nil {
+		if errors.Is(err, game.ErrGameConfigNotFound) {
+			return
+		}
+		logs.Fatal("parse gameConfig %s err:%v", routeDictKey, err)
+	}
+	if err := protocol.AddDictionary(dict); err != nil {
+		logs.Fatal("set route dictionary err:%v", err)
+	}
+	logs.Info("route dictionary loaded, %d routes", len(dict))
+}
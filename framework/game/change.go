@@ -0,0 +1,93 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// OnChange订阅的特殊key 其他的key对应gameConfig中的某一项
+const (
+	ServersKey    = servers    // servers.json整体变化 回调参数是ServersConf
+	GameConfigKey = gameConfig // gameConfig.json有任何一项变化 回调参数是GameConfig
+)
+
+// ChangeFunc 配置变化的回调 gameConfig中某一项的参数是*GameConfigValue 新增时old为nil 删除时new为nil
+// 回调在加载配置的协程中执行 不要阻塞
+type ChangeFunc func(old, new any)
+
+// OnChange 订阅配置的变化 key为ServersKey GameConfigKey或者gameConfig中的key
+//
+//	game.Conf.OnChange("startGold", func(old, new any) { ... })
+func (c *Config) OnChange(key string, fn ChangeFunc) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	if c.subs == nil {
+		c.subs = make(map[string][]ChangeFunc)
+	}
+	c.subs[key] = append(c.subs[key], fn)
+}
+
+func (c *Config) notify(key string, old, new any) {
+	c.subsMu.Lock()
+	subs := c.subs[key]
+	c.subsMu.Unlock()
+	for _, fn := range subs {
+		fn(old, new)
+	}
+}
+
+// notifyGameConfig 先按key通知变化的项 再通知整体的变化
+func (c *Config) notifyGameConfig(old, new GameConfig) {
+	changed := false
+	for k, v := range new {
+		if o, ok := old[k]; !ok || !o.equal(v) {
+			changed = true
+			c.notify(k, nilIfAbsent(old, k), v)
+		}
+	}
+	for k, o := range old {
+		if _, ok := new[k]; !ok {
+			changed = true
+			c.notify(k, o, nil)
+		}
+	}
+	if changed {
+		c.notify(GameConfigKey, old, new)
+	}
+}
+
+// nilIfAbsent 不存在时返回接口类型的nil 而不是值为nil的*GameConfigValue
+func nilIfAbsent(gc GameConfig, key string) any {
+	if v, ok := gc[key]; ok {
+		return v
+	}
+	return nil
+}
+
+func (v *GameConfigValue) equal(o *GameConfigValue) bool {
+	return v.Describe == o.Describe && v.Backend == o.Backend && jsonEqual(v.Value, o.Value)
+}
+
+// jsonEqual 忽略格式上的差异 例如空格和换行
+func jsonEqual(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
+// FrontChanged 下发给前端的配置是否变化 backend的项和描述的变化不影响前端
+func FrontChanged(old, new GameConfig) bool {
+	of, nf := old.Front(), new.Front()
+	if len(of) != len(nf) {
+		return true
+	}
+	for k, v := range nf {
+		o, ok := of[k]
+		if !ok || !jsonEqual(o, v) {
+			return true
+		}
+	}
+	return false
+}
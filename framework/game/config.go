@@ -2,14 +2,14 @@ package game
 
 import (
 	"common/logs"
-	"encoding/json"
 	"fmt"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
-	"io"
 	"log"
 	"os"
 	"path"
+	"sync"
+	"sync/atomic"
 )
 
 var Conf *Config
@@ -20,8 +20,10 @@ const (
 )
 
 type Config struct {
-	GameConfig  map[string]dGameConfigValue `json:"gameConfig"`
-	ServersConf ServersConf                 `json:"serversConf"`
+	gameConfig  atomic.Pointer[GameConfig]
+	serversConf atomic.Pointer[ServersConf]
+	subsMu      sync.Mutex
+	subs        map[string][]ChangeFunc
 }
 type ServersConf struct {
 	Nats       NatsConfig         `json:"nats" `
@@ -33,26 +35,77 @@ type ServersConf struct {
 type ServersConfig struct {
 	ID               string `json:"id" `
 	ServerType       string `json:"serverType" `
-	HandleTimeOut    int    `json:"handleTimeOut" `
-	RPCTimeOut       int    `json:"rpcTimeOut" `
-	MaxRunRoutineNum int    `json:"maxRunRoutineNum" `
+	HandleTimeOut    int    `json:"handleTimeOut" `    //handler处理的超时秒数
+	RPCTimeOut       int    `json:"rpcTimeOut" `       //调用其他服务器的超时秒数 例如推送等待connector的回复
+	MaxRunRoutineNum int    `json:"maxRunRoutineNum" ` //同时执行的handler数量
 }
 
 type ConnectorConfig struct {
-	ID         string `json:"id" `
-	Host       string `json:"host" `
-	ClientPort int    `json:"clientPort" `
-	Frontend   bool   `json:"frontend" `
-	ServerType string `json:"serverType" `
+	ID          string `json:"id" `
+	Host        string `json:"host" `
+	ClientPort  int    `json:"clientPort" `
+	Frontend    bool   `json:"frontend" `
+	HeartTime   int    `json:"heartTime" `
+	AuthTimeout int    `json:"authTimeout" ` //连接后需要在多少秒内完成认证
+	ServerType  string `json:"serverType" `
+	//写队列 长度 队列满了的策略dropOldest dropNewest disconnect 队列满时最多等待的毫秒数
+	WriteQueueSize int             `json:"writeQueueSize" `
+	WritePolicy    string          `json:"writePolicy" `
+	SendTimeout    int             `json:"sendTimeout" `
+	MaxMessageSize int64           `json:"maxMessageSize" ` //客户端单条消息的最大字节数
+	RateLimit      RateLimitConfig `json:"rateLimit" `
+	//证书和私钥都配置了使用wss 允许的Origin为空时不限制 压缩需要客户端也支持permessage-deflate
+	CertFile     string   `json:"certFile" `
+	KeyFile      string   `json:"keyFile" `
+	AllowOrigins []string `json:"allowOrigins" `
+	Compression  bool     `json:"compression" `
+	//客户端连接的地址 为空时使用host 下线时通知客户端重连到这里
+	ClientHost   string   `json:"clientHost" `
+	AdminPort    int      `json:"adminPort" `    //管理接口端口 0表示不开启
+	AdminHost    string   `json:"adminHost" `    //管理接口监听的地址 默认127.0.0.1 不要暴露到公网
+	AdminToken   string   `json:"adminToken" `   //管理接口的token 请求头带上Authorization: Bearer token 为空时不校验
+	DrainTimeout int      `json:"drainTimeout" ` //下线时等待请求处理完的秒数
+	ResumeGrace  int      `json:"resumeGrace" `  //断线后保留session的秒数 0表示不支持恢复
+	ResumeBuffer int      `json:"resumeBuffer" ` //断线期间最多缓存的推送数
+	Transport    string   `json:"transport" `    //客户端连接方式 ws tcp both 默认ws
+	TcpPort      int      `json:"tcpPort" `      //tcp监听的端口 只使用tcp时可以不配置 使用clientPort
+	Weight       int      `json:"weight" `       //注册到etcd的权重 gate选择connector时使用
+	Serializers  []string `json:"serializers" `  //允许客户端握手时协商的序列化方式 为空时允许所有注册的
+	RPCTimeOut   int      `json:"rpcTimeOut" `   //转发给后端的请求等待回复的秒数 默认5秒
+	//本地handler的超时秒数和同时执行的数量 和后端服务器的配置含义相同
+	HandleTimeOut    int `json:"handleTimeOut" `
+	MaxRunRoutineNum int `json:"maxRunRoutineNum" `
 }
-type NatsConfig struct {
-	Url string `json:"url" mapstructure:"db"`
+
+// RateLimitConfig 每个连接的发送频率限制 0表示不限制
+type RateLimitConfig struct {
+	MsgPerSecond   float64            `json:"msgPerSecond" `
+	BytesPerSecond float64            `json:"bytesPerSecond" `
+	Burst          float64            `json:"burst" `       //允许的突发消息数 默认等于msgPerSecond
+	MaxWarnings    int                `json:"maxWarnings" ` //警告超过这个次数后踢下线
+	Routes         []RouteLimitConfig `json:"routes" `      //按路由单独限制 代替连接的消息数限制 viper会把key中的点当成层级 所以用数组
 }
 
-type dGameConfigValue map[string]any
+type RouteLimitConfig struct {
+	Route        string  `json:"route" `
+	MsgPerSecond float64 `json:"msgPerSecond" `
+	Burst        float64 `json:"burst" `
+}
+type NatsConfig struct {
+	Url string `json:"url" mapstructure:"url"`
+}
 
-func InitConfig(configDir string) {
+// InitConfig 默认读取configDir中的servers.json和gameConfig.json 使用WithEtcd时从etcd读取
+func InitConfig(configDir string, opts ...Option) {
 	Conf = new(Config)
+	o := &initOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.etcd != nil {
+		initEtcdConfig(*o.etcd)
+		return
+	}
 	dir, err := os.ReadDir(configDir)
 	if err != nil {
 		logs.Fatal("read config dir err:%v", err)
@@ -60,7 +113,10 @@ func InitConfig(configDir string) {
 	for _, v := range dir {
 		configFile := path.Join(configDir, v.Name())
 		if v.Name() == gameConfig {
-			readGameConfig(configFile)
+			if err := readGameConfig(configFile); err != nil {
+				logs.Fatal("read gameConfig err:%v", err)
+			}
+			watchGameConfig(configFile)
 		}
 		if v.Name() == servers {
 			readServersConfig(configFile)
@@ -69,87 +125,60 @@ func InitConfig(configDir string) {
 }
 
 func readServersConfig(configFile string) {
-	var serversConfig ServersConf
 	v := viper.New()
 	v.SetConfigFile(configFile)
 	v.WatchConfig()
 	v.OnConfigChange(func(in fsnotify.Event) {
 		log.Println("serversConfig 配置文件被修改了")
-		err := v.Unmarshal(&serversConfig)
-		if err != nil {
-			panic(fmt.Errorf("serversConfig Unmarshal change config data,err:%v \n", err))
+		var serversConfig ServersConf
+		if err := v.Unmarshal(&serversConfig); err != nil {
+			logs.Error("serversConfig Unmarshal change config data, keep the old one, err:%v", err)
+			return
 		}
-		Conf.ServersConf = serversConfig
+		Conf.SetServersConf(serversConfig)
 	})
 	err := v.ReadInConfig()
 	if err != nil {
 		panic(fmt.Errorf("serversConfig 读取配置文件出错,err:%v \n", err))
 	}
 	//解析
+	var serversConfig ServersConf
 	err = v.Unmarshal(&serversConfig)
 	if err != nil {
 		panic(fmt.Errorf("serversConfig Unmarshal config data,err:%v \n", err))
 	}
-	Conf.ServersConf = serversConfig
-	typeServersConfig()
+	Conf.SetServersConf(serversConfig)
 }
 
-func typeServersConfig() {
-	if len(Conf.ServersConf.Servers) > 0 {
-		if Conf.ServersConf.TypeServer == nil {
-			Conf.ServersConf.TypeServer = make(map[string][]*ServersConfig)
-		}
-		for _, v := range Conf.ServersConf.Servers {
-			if Conf.ServersConf.TypeServer[v.ServerType] == nil {
-				Conf.ServersConf.TypeServer[v.ServerType] = make([]*ServersConfig, 0)
-			}
-			Conf.ServersConf.TypeServer[v.ServerType] = append(Conf.ServersConf.TypeServer[v.ServerType], v)
-		}
+// SetServersConf 先建好TypeServer再原子替换 然后通知订阅了ServersKey的回调
+// 配置文件和etcd重新加载时调用 也可以用于自定义的配置来源
+func (c *Config) SetServersConf(sc ServersConf) {
+	sc.TypeServer = typeServersConfig(sc.Servers)
+	var old ServersConf
+	if p := c.serversConf.Swap(&sc); p != nil {
+		old = *p
 	}
+	c.notify(ServersKey, old, sc)
 }
 
-func readGameConfig(configFile string) {
-	//gc := make(map[string]GameConfigValue)
-	//v := viper.New()
-	//v.SetConfigFile(configFile)
-	//v.WatchConfig()
-	//v.OnConfigChange(func(in fsnotify.Event) {
-	//	log.Println("gameConfig配置文件被修改了")
-	//	err := v.Unmarshal(&gc)
-	//	if err != nil {
-	//		panic(fmt.Errorf("gameConfig Unmarshal change config data,err:%v \n", err))
-	//	}
-	//	Conf.GameConfig = gc
-	//})
-	//err := v.ReadInConfig()
-	//if err != nil {
-	//	panic(fmt.Errorf("gameConfig 读取配置文件出错,err:%v \n", err))
-	//}
-	//log.Println("%v", v.AllKeys())
-	//err = v.Unmarshal(&gc)
-	//if err != nil {
-	//	panic(fmt.Errorf("gameConfig Unmarshal config data,err:%v \n", err))
-	//}
-	//Conf.GameConfig = gc
-	file, err := os.Open(configFile)
-	if err != nil {
-		panic(err)
+// ServersConf 当前生效的servers配置 重新加载时整体替换 不要修改返回的内容
+func (c *Config) ServersConf() *ServersConf {
+	if sc := c.serversConf.Load(); sc != nil {
+		return sc
 	}
-	defer file.Close()
-	data, err := io.ReadAll(file)
-	if err != nil {
-		panic(err)
-	}
-	var gc map[string]dGameConfigValue
-	err = json.Unmarshal(data, &gc)
-	if err != nil {
-		panic(err)
+	return &ServersConf{}
+}
+
+func typeServersConfig(list []*ServersConfig) map[string][]*ServersConfig {
+	typeServer := make(map[string][]*ServersConfig)
+	for _, v := range list {
+		typeServer[v.ServerType] = append(typeServer[v.ServerType], v)
 	}
-	Conf.GameConfig = gc
+	return typeServer
 }
 
 func (c *Config) GetConnector(serverId string) *ConnectorConfig {
-	for _, v := range c.ServersConf.Connector {
+	for _, v := range c.ServersConf().Connector {
 		if v.ID == serverId {
 			return v
 		}
@@ -157,27 +186,20 @@ func (c *Config) GetConnector(serverId string) *ConnectorConfig {
 	return nil
 }
 
-func (c *Config) GetConnectorByServerType(serverType string) *ConnectorConfig {
-	for _, v := range c.ServersConf.Connector {
-		if v.ServerType == serverType {
+func (c *Config) GetServer(serverId string) *ServersConfig {
+	for _, v := range c.ServersConf().Servers {
+		if v.ID == serverId {
 			return v
 		}
 	}
 	return nil
 }
 
-func (c *Config) GetFrontGameConfig() map[string]any {
-	result := make(map[string]any)
-	for k, v := range c.GameConfig {
-		value, ok := v["value"]
-		backend := false
-		_, exist := v["backend"]
-		if exist {
-			backend = v["backend"].(bool)
-		}
-		if ok && !backend {
-			result[k] = value
+func (c *Config) GetConnectorByServerType(serverType string) *ConnectorConfig {
+	for _, v := range c.ServersConf().Connector {
+		if v.ServerType == serverType {
+			return v
 		}
 	}
-	return result
+	return nil
 }
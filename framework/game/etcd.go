@@ -0,0 +1,276 @@
+package game
+
+import (
+	"common/config"
+	"common/logs"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEtcdConfigPrefix = "/config/"
+	etcdSyncInterval        = time.Minute
+)
+
+// EtcdDocument etcd中保存的配置 data是servers.json或gameConfig.json的原文 每次发布version加1
+type EtcdDocument struct {
+	Version     int64           `json:"version"`
+	PublishTime int64           `json:"publishTime"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Option InitConfig的可选项
+type Option func(*initOptions)
+
+type initOptions struct {
+	etcd *config.EtcdConf
+}
+
+// WithEtcd 从etcd读取servers.json和gameConfig.json并监听变化 不使用configDir中的文件
+// key为 conf.ConfigPrefix + 文件名 前缀默认是/config/
+func WithEtcd(conf config.EtcdConf) Option {
+	return func(o *initOptions) {
+		o.etcd = &conf
+	}
+}
+
+// EtcdConfigKey 配置文件在etcd中的key
+func EtcdConfigKey(conf config.EtcdConf, name string) string {
+	prefix := conf.ConfigPrefix
+	if prefix == "" {
+		prefix = defaultEtcdConfigPrefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix + name
+}
+
+// NewEtcdClient 使用EtcdConf中的集群地址
+func NewEtcdClient(conf config.EtcdConf) (*clientv3.Client, error) {
+	dialTimeout := conf.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 3
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   conf.Addrs,
+		DialTimeout: time.Duration(dialTimeout) * time.Second,
+	})
+}
+
+// ValidateConfig 按文件名校验配置 发布到etcd和从etcd加载时都会校验
+func ValidateConfig(name string, data []byte) error {
+	switch name {
+	case gameConfig:
+		_, err := ParseGameConfig(data)
+		return err
+	case servers:
+		_, err := ParseServersConf(data)
+		return err
+	}
+	return fmt.Errorf("unknown config %s", name)
+}
+
+// ParseServersConf 解析并校验servers.json id不能为空也不能重复
+func ParseServersConf(data []byte) (ServersConf, error) {
+	var sc ServersConf
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return sc, fmt.Errorf("servers is not valid json: %w", err)
+	}
+	var errs []error
+	if sc.Nats.Url == "" {
+		errs = append(errs, errors.New("nats.url is required"))
+	}
+	ids := make(map[string]bool)
+	checkId := func(kind string, i int, id string) {
+		if id == "" {
+			errs = append(errs, fmt.Errorf("%s[%d]: id is required", kind, i))
+			return
+		}
+		if ids[id] {
+			errs = append(errs, fmt.Errorf("%s[%d]: duplicate id %s", kind, i, id))
+		}
+		ids[id] = true
+	}
+	for i, v := range sc.Connector {
+		checkId("connector", i, v.ID)
+	}
+	for i, v := range sc.Servers {
+		checkId("servers", i, v.ID)
+		if v.ServerType == "" {
+			errs = append(errs, fmt.Errorf("servers[%d]: serverType is required", i))
+		}
+	}
+	if len(errs) > 0 {
+		return sc, errors.Join(errs...)
+	}
+	return sc, nil
+}
+
+// PublishConfig 校验后发布到etcd version为0时在当前版本上加1 否则必须大于当前版本
+// 使用事务比较修改版本 并发发布时只有一个成功 返回发布的版本
+func PublishConfig(ctx context.Context, cli *clientv3.Client, conf config.EtcdConf, name string, data []byte, version int64) (int64, error) {
+	if err := ValidateConfig(name, data); err != nil {
+		return 0, err
+	}
+	key := EtcdConfigKey(conf, name)
+	res, err := cli.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	var modRevision int64
+	if len(res.Kvs) > 0 {
+		var doc EtcdDocument
+		if err := json.Unmarshal(res.Kvs[0].Value, &doc); err != nil {
+			return 0, fmt.Errorf("parse %s err: %w", key, err)
+		}
+		current = doc.Version
+		modRevision = res.Kvs[0].ModRevision
+	}
+	if version == 0 {
+		version = current + 1
+	} else if version <= current {
+		return 0, fmt.Errorf("%s version %d must be greater than current version %d", name, version, current)
+	}
+	value, err := json.Marshal(EtcdDocument{
+		Version:     version,
+		PublishTime: time.Now().Unix(),
+		Data:        data,
+	})
+	if err != nil {
+		return 0, err
+	}
+	txn, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRevision)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !txn.Succeeded {
+		return 0, fmt.Errorf("%s was modified by others while publishing, retry", name)
+	}
+	return version, nil
+}
+
+// etcdSource 从etcd加载配置 记录每个文件已经加载的版本 同一版本不重复加载
+type etcdSource struct {
+	conf     config.EtcdConf
+	cli      *clientv3.Client
+	versions map[string]int64
+}
+
+func initEtcdConfig(conf config.EtcdConf) {
+	cli, err := NewEtcdClient(conf)
+	if err != nil {
+		logs.Fatal("connect etcd for config err:%v", err)
+	}
+	s := &etcdSource{
+		conf:     conf,
+		cli:      cli,
+		versions: make(map[string]int64),
+	}
+	//启动时两个配置都必须存在并且校验通过
+	for _, name := range []string{servers, gameConfig} {
+		if err := s.sync(name); err != nil {
+			logs.Fatal("load %s from etcd err:%v", name, err)
+		}
+	}
+	go s.watch()
+}
+
+func (s *etcdSource) rwTimeout() time.Duration {
+	if s.conf.RWTimeout <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(s.conf.RWTimeout) * time.Second
+}
+
+func (s *etcdSource) sync(name string) error {
+	key := EtcdConfigKey(s.conf, name)
+	ctx, cancel := context.WithTimeout(context.Background(), s.rwTimeout())
+	defer cancel()
+	res, err := s.cli.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(res.Kvs) == 0 {
+		return fmt.Errorf("%s not found in etcd", key)
+	}
+	return s.load(name, res.Kvs[0].Value)
+}
+
+// load 校验失败时保留原来的配置
+func (s *etcdSource) load(name string, value []byte) error {
+	var doc EtcdDocument
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return fmt.Errorf("parse etcd document err: %w", err)
+	}
+	if v, ok := s.versions[name]; ok && v == doc.Version {
+		return nil
+	}
+	switch name {
+	case gameConfig:
+		if err := Conf.LoadGameConfig(doc.Data); err != nil {
+			return err
+		}
+	case servers:
+		sc, err := ParseServersConf(doc.Data)
+		if err != nil {
+			return err
+		}
+		Conf.SetServersConf(sc)
+	}
+	s.versions[name] = doc.Version
+	logs.Info("%s version %d loaded from etcd", name, doc.Version)
+	return nil
+}
+
+// watch 同一个协程处理watch事件和定时同步 watch可能丢事件 定时全量同步一次
+func (s *etcdSource) watch() {
+	names := map[string]string{
+		EtcdConfigKey(s.conf, servers):    servers,
+		EtcdConfigKey(s.conf, gameConfig): gameConfig,
+	}
+	prefix := EtcdConfigKey(s.conf, "")
+	watchCh := s.cli.Watch(context.Background(), prefix, clientv3.WithPrefix())
+	ticker := time.NewTicker(etcdSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case res, ok := <-watchCh:
+			if !ok || res.Err() != nil {
+				//例如版本被压缩 重新watch 期间的变化靠下次定时同步
+				logs.Error("watch config in etcd err:%v, rewatch", res.Err())
+				time.Sleep(time.Second)
+				watchCh = s.cli.Watch(context.Background(), prefix, clientv3.WithPrefix())
+				continue
+			}
+			for _, ev := range res.Events {
+				name, ok := names[string(ev.Kv.Key)]
+				if !ok {
+					continue
+				}
+				if ev.Type == clientv3.EventTypeDelete {
+					logs.Warn("%s deleted from etcd, keep the old one", name)
+					continue
+				}
+				if err := s.load(name, ev.Kv.Value); err != nil {
+					logs.Error("reload %s from etcd failed, keep the old one, err:%v", name, err)
+				}
+			}
+		case <-ticker.C:
+			for _, name := range []string{servers, gameConfig} {
+				if err := s.sync(name); err != nil {
+					logs.Error("sync %s from etcd failed, err:%v", name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package game
+
+import (
+	"bytes"
+	"common/logs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"google.golang.org/protobuf/types/known/structpb"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const reloadDelay = 200 * time.Millisecond // 文件修改后等待写完再加载
+
+var ErrGameConfigNotFound = errors.New("game config not found")
+
+// GameConfigValue gameConfig.json中的一项 value可以是任意json backend为true的不下发给前端
+//
+//	"startGold": {"value": 10000, "describe": "新注册用户初始金币数量", "backend": true}
+type GameConfigValue struct {
+	Value    json.RawMessage `json:"value"`
+	Describe string          `json:"describe"`
+	Backend  bool            `json:"backend"`
+}
+
+// GameConfig gameConfig.json中的所有配置 key -> 配置项
+type GameConfig map[string]*GameConfigValue
+
+// ParseGameConfig 解析并校验gameConfig 每一项只能有value describe backend
+// value必须存在且不为null describe必须是字符串 backend必须是bool 所有错误一起返回
+func ParseGameConfig(data []byte) (GameConfig, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("gameConfig is not a json object: %w", err)
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	gc := make(GameConfig, len(raw))
+	var errs []error
+	for _, k := range keys {
+		v, err := parseGameConfigValue(raw[k])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gameConfig[%s]: %w", k, err))
+			continue
+		}
+		gc[k] = v
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return gc, nil
+}
+
+func parseGameConfigValue(data []byte) (*GameConfigValue, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, errors.New("entry must be an object")
+	}
+	v := &GameConfigValue{}
+	for name, field := range fields {
+		switch name {
+		case "value":
+			if isNull(field) {
+				return nil, errors.New("value must not be null")
+			}
+			v.Value = field
+		case "describe":
+			if err := json.Unmarshal(field, &v.Describe); err != nil {
+				return nil, errors.New("describe must be a string")
+			}
+		case "backend":
+			if err := json.Unmarshal(field, &v.Backend); err != nil {
+				return nil, errors.New("backend must be a bool")
+			}
+		default:
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	if v.Value == nil {
+		return nil, errors.New("value is required")
+	}
+	return v, nil
+}
+
+func isNull(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
+
+// LoadGameConfig 校验通过后原子替换 然后通知订阅了变化的key 校验失败时保留原来的配置
+func (c *Config) LoadGameConfig(data []byte) error {
+	gc, err := ParseGameConfig(data)
+	if err != nil {
+		return err
+	}
+	var old GameConfig
+	if p := c.gameConfig.Swap(&gc); p != nil {
+		old = *p
+	}
+	c.notifyGameConfig(old, gc)
+	return nil
+}
+
+// GameConfig 当前生效的gameConfig 不要修改返回的内容
+func (c *Config) GameConfig() GameConfig {
+	gc := c.gameConfig.Load()
+	if gc == nil {
+		return nil
+	}
+	return *gc
+}
+
+func (c *Config) gameValue(key string) (*GameConfigValue, bool) {
+	v, ok := c.GameConfig()[key]
+	return v, ok
+}
+
+// GetString 获取gameConfig中某一项的value 不存在或不是字符串时返回空
+func (c *Config) GetString(key string) string {
+	v, ok := c.gameValue(key)
+	if !ok {
+		return ""
+	}
+	var s string
+	_ = json.Unmarshal(v.Value, &s)
+	return s
+}
+
+// GetInt 数字和数字字符串都可以 不存在或者不能转换时返回0
+func (c *Config) GetInt(key string) int {
+	v, ok := c.gameValue(key)
+	if !ok {
+		return 0
+	}
+	var f float64
+	if err := json.Unmarshal(v.Value, &f); err == nil {
+		return int(f)
+	}
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	return 0
+}
+
+// GetBool 配置中有 "false" 这样的字符串 同样可以解析 不存在或者不能转换时返回false
+func (c *Config) GetBool(key string) bool {
+	v, ok := c.gameValue(key)
+	if !ok {
+		return false
+	}
+	var b bool
+	if err := json.Unmarshal(v.Value, &b); err == nil {
+		return b
+	}
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		b, _ = strconv.ParseBool(s)
+	}
+	return b
+}
+
+// GetStruct 把value解码到out中 out需要是指针
+//
+//	var sms SmsAuthConfig
+//	err := game.Conf.GetStruct("smsAuthConfig", &sms)
+func (c *Config) GetStruct(key string, out any) error {
+	v, ok := c.gameValue(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrGameConfigNotFound, key)
+	}
+	return json.Unmarshal(v.Value, out)
+}
+
+// Front 下发给前端的配置 不包括backend为true的
+func (gc GameConfig) Front() map[string]json.RawMessage {
+	result := make(map[string]json.RawMessage)
+	for k, v := range gc {
+		if !v.Backend {
+			result[k] = v.Value
+		}
+	}
+	return result
+}
+
+// FrontValues 下发给前端的配置 value解码成map[string]any float64 string这些普通的值
+func (gc GameConfig) FrontValues() (map[string]any, error) {
+	front := make(map[string]any)
+	for k, raw := range gc.Front() {
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("gameConfig[%s]: %w", k, err)
+		}
+		front[k] = v
+	}
+	return front, nil
+}
+
+// FrontStruct 下发给前端的配置转成structpb json和protobuf的客户端都可以解码
+// json客户端收到的和GetFrontGameConfig一样 protobuf客户端收到google.protobuf.Struct
+func (gc GameConfig) FrontStruct() (*structpb.Struct, error) {
+	front, err := gc.FrontValues()
+	if err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(front)
+}
+
+// GetFrontGameConfig 下发给前端的配置 不包括backend为true的 value是解码后的普通值
+func (c *Config) GetFrontGameConfig() map[string]any {
+	front, err := c.GameConfig().FrontValues()
+	if err != nil {
+		//加载时已经校验过是合法的json 不会走到这里
+		logs.Error("decode front game config err:%v", err)
+		return make(map[string]any)
+	}
+	return front
+}
+
+func readGameConfig(configFile string) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	return Conf.LoadGameConfig(data)
+}
+
+// watchGameConfig 监听gameConfig.json 修改后重新加载 校验失败时继续使用原来的配置
+// 编辑器保存时可能先删除再创建文件 所以监听所在的目录 短时间内的多次修改只加载一次
+func watchGameConfig(configFile string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logs.Error("watch gameConfig err:%v", err)
+		return
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		logs.Error("watch gameConfig err:%v", err)
+		watcher.Close()
+		return
+	}
+	reload := func() {
+		if err := readGameConfig(configFile); err != nil {
+			logs.Error("reload gameConfig failed, keep the old one, err:%v", err)
+			return
+		}
+		logs.Info("gameConfig reloaded")
+	}
+	//加载在监听的协程中执行 两次加载不会同时进行
+	go func() {
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != filepath.Clean(configFile) || ev.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				timer.Reset(reloadDelay)
+			case <-timer.C:
+				reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logs.Error("watch gameConfig err:%v", err)
+			}
+		}
+	}()
+}
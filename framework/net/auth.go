@@ -0,0 +1,101 @@
+package net
+
+import (
+	"common/biz"
+	"common/config"
+	"common/jwts"
+	"common/logs"
+	"framework/myError"
+	"framework/protocol"
+	"framework/protocol/pb"
+	"time"
+)
+
+const (
+	DefaultAuthRoute   = "connector.entryHandler.entry"
+	defaultAuthTimeout = 10 * time.Second
+)
+
+// AuthReq 认证请求 token由gate登录时下发
+type AuthReq = pb.AuthReq
+
+type AuthRes = pb.AuthRes
+
+// parseToken 校验gate签发的jwt 返回uid
+func parseToken(token string) (string, *myError.Error) {
+	uid, err := jwts.ParseToken(token, config.Conf.Jwt.Secret)
+	if err != nil || uid == "" {
+		logs.Warn("parse token err:%v", err)
+		return "", biz.TokenInfoError
+	}
+	return uid, nil
+}
+
+// bindUser 认证通过后把用户绑定到session上 同一个用户之前的连接会被踢下线
+// 共享注册表的读写在单独的协程中 不阻塞认证的回复
+func (m *Manager) bindUser(c Connection, uid string) {
+	cid := c.GetSession().Cid()
+	m.kickDuplicated(uid, cid)
+	c.GetSession().Bind(uid)
+	m.syncUser(c)
+	m.registerUser(uid, cid)
+}
+
+// authHandler 处理认证请求 认证通过后如果注册了同一路由的handler 继续交给handler处理
+func (m *Manager) authHandler(c Connection, message *protocol.Message) {
+	var req AuthReq
+	if err := c.GetSession().Serializer().Unmarshal(message.Data, &req); err != nil {
+		m.response(c, message, nil, biz.RequestDataError)
+		m.kickUnauthorized(c)
+		return
+	}
+	uid, e := parseToken(req.Token)
+	if e != nil {
+		m.response(c, message, nil, e)
+		m.kickUnauthorized(c)
+		return
+	}
+	//已经认证过的连接不能换成其他用户 否则旧用户的在线记录和channel都会残留
+	if current := c.GetSession().Uid(); current != "" {
+		if uid != current {
+			logs.Warn("client[%s] user[%s] auth again as user[%s]", c.GetSession().Cid(), current, uid)
+			m.response(c, message, nil, biz.InvalidUsers)
+			return
+		}
+		m.response(c, message, &AuthRes{Uid: uid}, nil)
+		return
+	}
+	m.bindUser(c, uid)
+	if handler, ok := m.ConnectorHandlers[message.Route]; ok {
+		m.runLocal(c, message, handler)
+		return
+	}
+	m.response(c, message, &AuthRes{Uid: uid}, nil)
+}
+
+func (m *Manager) kickUnauthorized(c Connection) {
+	m.Kick(c, protocol.KickBody{
+		Code:   protocol.KickUnauthorized,
+		Reason: "unauthorized",
+	})
+}
+
+// checkAuthTimeout 连接后超时还没有认证的直接断开
+func (m *Manager) checkAuthTimeout(c Connection) {
+	timeout := m.AuthTimeout
+	if timeout <= 0 {
+		timeout = defaultAuthTimeout
+	}
+	time.AfterFunc(timeout, func() {
+		if _, ok := m.getClient(c.GetSession().Cid()); !ok {
+			return
+		}
+		if c.GetSession().Uid() == "" {
+			logs.Warn("client[%s] auth timeout", c.GetSession().Cid())
+			m.Kick(c, protocol.KickBody{
+				Code:   protocol.KickAuthTimeout,
+				Reason: "auth timeout",
+			})
+		}
+	})
+}
@@ -0,0 +1,206 @@
+package net
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrChannelNoUid     = errors.New("session not bind uid")
+	ErrChannelDestroyed = errors.New("channel destroyed")
+)
+
+// Pusher 按connector分组推送消息 返回推送失败的uid
+// connector的Manager和后端服务器的node.App都实现了这个接口
+type Pusher interface {
+	PushToServers(route string, data any, uidsByServer map[string][]string) ([]string, error)
+}
+
+// Member channel中的成员 记录成员所在的connector 广播时按connector分组发送
+type Member struct {
+	Uid         string
+	Cid         string
+	ConnectorId string
+}
+
+// Channel 一组用户 例如一张牌桌上的所有玩家
+type Channel struct {
+	sync.RWMutex
+	name      string
+	members   map[string]*Member // uid -> member
+	service   *ChannelService
+	destroyed bool // 已经从service中删除 由service的锁保护
+}
+
+func (c *Channel) Name() string {
+	return c.name
+}
+
+// Add 把session对应的用户加入channel session必须已经绑定uid
+// 获取之后channel可能因为最后一个成员离开被销毁 这时返回ErrChannelDestroyed 需要重新获取 或者使用ChannelService.Join
+func (c *Channel) Add(session *Session) error {
+	c.service.Lock()
+	defer c.service.Unlock()
+	if c.destroyed {
+		return ErrChannelDestroyed
+	}
+	return c.add(session)
+}
+
+// add 调用方持有service的锁 保证加入时channel没有被销毁
+func (c *Channel) add(session *Session) error {
+	uid := session.Uid()
+	if uid == "" {
+		return ErrChannelNoUid
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.members[uid] = &Member{
+		Uid:         uid,
+		Cid:         session.Cid(),
+		ConnectorId: session.ConnectorId(),
+	}
+	return nil
+}
+
+// Leave 用户离开channel 最后一个成员离开时销毁channel
+func (c *Channel) Leave(uid string) {
+	c.Lock()
+	delete(c.members, uid)
+	empty := len(c.members) == 0
+	c.Unlock()
+	if empty {
+		c.service.destroyIfEmpty(c.name)
+	}
+}
+
+func (c *Channel) Members() []Member {
+	c.RLock()
+	defer c.RUnlock()
+	members := make([]Member, 0, len(c.members))
+	for _, v := range c.members {
+		members = append(members, *v)
+	}
+	return members
+}
+
+func (c *Channel) Contains(uid string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, ok := c.members[uid]
+	return ok
+}
+
+func (c *Channel) Len() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.members)
+}
+
+// Broadcast 推送给channel中除了exclude以外的所有成员 成员可以分布在不同的connector上
+// 返回推送失败的uid
+func (c *Channel) Broadcast(route string, data any, exclude ...string) ([]string, error) {
+	skip := make(map[string]struct{}, len(exclude))
+	for _, v := range exclude {
+		skip[v] = struct{}{}
+	}
+	groups := make(map[string][]string)
+	c.RLock()
+	for uid, m := range c.members {
+		if _, ok := skip[uid]; ok {
+			continue
+		}
+		groups[m.ConnectorId] = append(groups[m.ConnectorId], uid)
+	}
+	c.RUnlock()
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return c.service.pusher.PushToServers(route, data, groups)
+}
+
+// ChannelService 管理所有的channel
+type ChannelService struct {
+	sync.RWMutex
+	channels map[string]*Channel
+	pusher   Pusher
+}
+
+func NewChannelService(pusher Pusher) *ChannelService {
+	return &ChannelService{
+		channels: make(map[string]*Channel),
+		pusher:   pusher,
+	}
+}
+
+// GetChannel 获取channel 不存在并且create为true时创建
+func (s *ChannelService) GetChannel(name string, create bool) (*Channel, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.getChannel(name, create)
+}
+
+func (s *ChannelService) getChannel(name string, create bool) (*Channel, bool) {
+	c, ok := s.channels[name]
+	if ok || !create {
+		return c, ok
+	}
+	c = &Channel{
+		name:    name,
+		members: make(map[string]*Member),
+		service: s,
+	}
+	s.channels[name] = c
+	return c, true
+}
+
+// Join 获取或创建channel并加入 整个过程持有锁 不会加入到刚被销毁的channel
+func (s *ChannelService) Join(name string, session *Session) (*Channel, error) {
+	s.Lock()
+	defer s.Unlock()
+	c, _ := s.getChannel(name, true)
+	if err := c.add(session); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *ChannelService) DestroyChannel(name string) {
+	s.Lock()
+	defer s.Unlock()
+	if c, ok := s.channels[name]; ok {
+		c.destroyed = true
+		delete(s.channels, name)
+	}
+}
+
+func (s *ChannelService) destroyIfEmpty(name string) {
+	s.Lock()
+	defer s.Unlock()
+	if c, ok := s.channels[name]; ok && c.Len() == 0 {
+		c.destroyed = true
+		delete(s.channels, name)
+	}
+}
+
+// MemberOffline 连接断开时把成员从所有channel中移除 已经被新连接替换的成员不受影响
+func (s *ChannelService) MemberOffline(uid, cid string) {
+	s.RLock()
+	channels := make([]*Channel, 0, len(s.channels))
+	for _, c := range s.channels {
+		channels = append(channels, c)
+	}
+	s.RUnlock()
+	for _, c := range channels {
+		c.Lock()
+		m, ok := c.members[uid]
+		if ok && m.Cid == cid {
+			delete(c.members, uid)
+		}
+		empty := len(c.members) == 0
+		c.Unlock()
+		if ok && empty {
+			s.destroyIfEmpty(c.name)
+		}
+	}
+}
@@ -1,12 +1,36 @@
 package net
 
+import "framework/protocol"
+
 type Connection interface {
 	Close()
 	SendMessage(buf []byte) error
-	//GetSession() *Session
+	GetSession() *Session
+}
+
+// noWaitSender 写队列满时不等待的连接 广播时使用
+type noWaitSender interface {
+	trySendMessage(buf []byte) error
+}
+
+// sendNoWait 广播给多个连接时使用 队列满时直接按写队列的策略处理 不等待SendTimeout
+func sendNoWait(c Connection, buf []byte) error {
+	if s, ok := c.(noWaitSender); ok {
+		return s.trySendMessage(buf)
+	}
+	return c.SendMessage(buf)
 }
 
 type MsgPack struct {
 	Cid  string
 	Body []byte
 }
+
+// encodeData 把消息编码成完整的Data包
+func encodeData(message *protocol.Message) ([]byte, error) {
+	body, err := protocol.MessageEncode(message)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.Encode(protocol.Data, body)
+}
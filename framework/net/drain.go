@@ -0,0 +1,97 @@
+package net
+
+import (
+	"common/logs"
+	"context"
+	"framework/protocol"
+	"framework/protocol/pb"
+	"time"
+)
+
+const (
+	drainCheckInterval = 100 * time.Millisecond
+	drainCloseWait     = 3 * time.Second // 关闭连接后等待清理session 通知后端下线
+)
+
+// Draining 是否正在下线
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// StopAccepting 下线的第一步 拒绝新的websocket升级和tcp连接
+func (m *Manager) StopAccepting() {
+	if m.draining.Swap(true) {
+		return
+	}
+	m.closeTCP()
+}
+
+// Drain 优雅下线 不再接受新连接 通知客户端重连到target
+// 等待处理中的请求完成 或者ctx到期后 关闭所有连接和http服务 只执行一次
+func (m *Manager) Drain(ctx context.Context, target protocol.ReconnectBody) {
+	m.drainOnce.Do(func() { m.drain(ctx, target) })
+}
+
+func (m *Manager) drain(ctx context.Context, target protocol.ReconnectBody) {
+	m.StopAccepting()
+	logs.Info("connector %s draining, reconnect to %s:%d", m.ServerId, target.Host, target.Port)
+	m.pushReconnect(target)
+	m.waitInflight(ctx)
+	m.Close()
+	m.waitClosed()
+	if m.server != nil {
+		//websocket连接已经被接管 这里只关闭监听
+		if err := m.server.Close(); err != nil {
+			logs.Warn("connector %s shutdown http server err:%v", m.ServerId, err)
+		}
+	}
+	logs.Info("connector %s drained", m.ServerId)
+}
+
+// pushReconnect target是websocket的地址 tcp客户端不指定地址 回到gate重新获取
+func (m *Manager) pushReconnect(target protocol.ReconnectBody) {
+	body := newPushBody(&pb.ReconnectBody{
+		Host:   target.Host,
+		Port:   int32(target.Port),
+		Secure: target.Secure,
+		Reason: target.Reason,
+	})
+	tcpBody := newPushBody(&pb.ReconnectBody{Reason: target.Reason})
+	for _, c := range m.allClients() {
+		b := body
+		if _, ok := c.(*TcpConnection); ok {
+			b = tcpBody
+		}
+		if err := m.pushNoWait(c, protocol.ReconnectRoute, b); err != nil {
+			logs.Warn("client[%s] push reconnect err:%v", c.GetSession().Cid(), err)
+		}
+	}
+}
+
+// waitInflight 客户端收到重连通知后可能还有请求没有处理完 等它们处理完
+func (m *Manager) waitInflight(ctx context.Context) {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for m.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			logs.Warn("connector %s drain deadline reached, %d requests still in flight", m.ServerId, m.inflight.Load())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitClosed 等待连接的读协程退出 清理完在线用户并通知后端服务器
+func (m *Manager) waitClosed() {
+	deadline := time.Now().Add(drainCloseWait)
+	for time.Now().Before(deadline) {
+		m.RLock()
+		n := len(m.clients)
+		m.RUnlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(drainCheckInterval)
+	}
+}
@@ -0,0 +1,81 @@
+package net
+
+import (
+	"common/biz"
+	"common/logs"
+	"context"
+	"framework/myError"
+	"framework/protocol/pb"
+	"framework/serializer"
+	"time"
+)
+
+// HandlerFunc 处理某一个路由的消息 返回值会编码后回复给客户端
+type HandlerFunc func(session *Session, body []byte) (any, *myError.Error)
+
+// LogicHandler 路由 -> 处理函数 路由格式 serverType.handler.method
+type LogicHandler map[string]HandlerFunc
+
+// Handle 把强类型的处理函数包装成HandlerFunc 先按session协商的序列化方式把消息体解码到Req中再调用
+// 需要支持protobuf客户端的路由 Req和返回值使用.proto生成的类型 json客户端同样可以使用
+//
+//	handlers["connector.entryHandler.entry"] = net.Handle(h.Entry)
+func Handle[Req any](fn func(session *Session, req *Req) (any, *myError.Error)) HandlerFunc {
+	return func(session *Session, body []byte) (any, *myError.Error) {
+		req := new(Req)
+		if len(body) > 0 {
+			if err := session.Serializer().Unmarshal(body, req); err != nil {
+				return nil, biz.RequestDataError
+			}
+		}
+		return fn(session, req)
+	}
+}
+
+// CallWithTimeout 在timeout内执行handler 超时返回的running在handler真正结束时关闭 timeout为0表示不限制
+// handler可以通过session.Context()感知超时 connector和后端服务器共用
+func CallWithTimeout(handler HandlerFunc, session *Session, body []byte, timeout time.Duration) (any, *myError.Error, <-chan struct{}) {
+	if timeout <= 0 {
+		res, e := handler(session, body)
+		return res, e, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	session = session.WithContext(ctx)
+	var res any
+	var e *myError.Error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, e = handler(session, body)
+	}()
+	select {
+	case <-done:
+		return res, e, nil
+	case <-ctx.Done():
+		return nil, nil, done
+	}
+}
+
+// ErrorBody 错误响应的消息体 和http接口的返回格式保持一致
+type ErrorBody = pb.ErrorBody
+
+// EncodeResult 编码处理结果 出错时返回错误标识 connector和后端服务器共用
+func EncodeResult(s serializer.Serializer, res any, err *myError.Error) ([]byte, bool) {
+	if err != nil {
+		data, _ := s.Marshal(&ErrorBody{
+			Code: int32(err.Code),
+			Msg:  err.Err.Error(),
+		})
+		return data, true
+	}
+	if res == nil {
+		return nil, false
+	}
+	data, e := s.Marshal(res)
+	if e != nil {
+		logs.Error("encode result with %s err:%v", s.Name(), e)
+		return EncodeResult(s, nil, biz.Fail)
+	}
+	return data, false
+}
@@ -0,0 +1,99 @@
+package net
+
+import (
+	"common/biz"
+	"context"
+	"framework/myError"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCallWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		handler HandlerFunc
+		res     any
+		err     *myError.Error
+		running bool
+	}{
+		{
+			name:    "no timeout",
+			handler: func(*Session, []byte) (any, *myError.Error) { return "ok", nil },
+			res:     "ok",
+		},
+		{
+			name:    "finish in time",
+			timeout: time.Second,
+			handler: func(*Session, []byte) (any, *myError.Error) { return "ok", nil },
+			res:     "ok",
+		},
+		{
+			name:    "handler error",
+			timeout: time.Second,
+			handler: func(*Session, []byte) (any, *myError.Error) { return nil, biz.Fail },
+			err:     biz.Fail,
+		},
+		{
+			name:    "timeout",
+			timeout: 20 * time.Millisecond,
+			handler: func(*Session, []byte) (any, *myError.Error) {
+				<-release
+				return "late", nil
+			},
+			running: true,
+		},
+		{
+			name:    "context deadline",
+			timeout: 20 * time.Millisecond,
+			handler: func(s *Session, _ []byte) (any, *myError.Error) {
+				<-s.Context().Done()
+				return nil, nil
+			},
+			running: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, e, running := CallWithTimeout(tt.handler, NewSession("c1", "connector-test"), nil, tt.timeout)
+			if res != tt.res || e != tt.err || (running != nil) != tt.running {
+				t.Fatalf("CallWithTimeout = %v, %v, running %t, want %v, %v, running %t", res, e, running != nil, tt.res, tt.err, tt.running)
+			}
+		})
+	}
+}
+
+func TestCallWithTimeoutContext(t *testing.T) {
+	session := NewSession("c1", "connector-test")
+	var wg sync.WaitGroup
+	ctxs := make([]context.Context, 2)
+	for i := range ctxs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			CallWithTimeout(func(s *Session, _ []byte) (any, *myError.Error) {
+				ctxs[i] = s.Context()
+				s.Put("key", i)
+				return nil, nil
+			}, session, nil, time.Second)
+		}()
+	}
+	wg.Wait()
+	if ctxs[0] == ctxs[1] {
+		t.Fatal("concurrent handlers share a context")
+	}
+	for i, ctx := range ctxs {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatalf("handler %d context has no deadline", i)
+		}
+	}
+	if _, ok := session.Context().Deadline(); ok {
+		t.Fatal("handler context stored on the session")
+	}
+	if _, ok := session.Get("key"); !ok {
+		t.Fatal("session data not shared with handlers")
+	}
+}
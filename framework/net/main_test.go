@@ -0,0 +1,50 @@
+package net
+
+import (
+	"common/config"
+	"common/logs"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	config.Conf = &config.Config{}
+	logs.InitLog("net_test")
+	os.Exit(m.Run())
+}
+
+// fakeConn 记录发出的消息 不经过网络
+type fakeConn struct {
+	sync.Mutex
+	session *Session
+	sent    [][]byte
+	closed  bool
+}
+
+func newFakeConn(cid string) *fakeConn {
+	return &fakeConn{session: NewSession(cid, "connector-test")}
+}
+
+func (c *fakeConn) Close() {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+}
+
+func (c *fakeConn) SendMessage(buf []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	c.sent = append(c.sent, buf)
+	return nil
+}
+
+func (c *fakeConn) GetSession() *Session {
+	return c.session
+}
+
+func (c *fakeConn) messages() [][]byte {
+	c.Lock()
+	defer c.Unlock()
+	return append([][]byte(nil), c.sent...)
+}
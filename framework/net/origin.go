@@ -0,0 +1,52 @@
+package net
+
+import (
+	"common/logs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginAllowlist 按白名单校验浏览器的Origin
+// 支持完整的 https://h5.example.com 也支持只写域名 h5.example.com 以及通配 *.example.com
+// 白名单为空或包含*时不限制 没有Origin头的请求不是浏览器发起的 直接放行
+func OriginAllowlist(origins []string) CheckOriginHandler {
+	allowAll := len(origins) == 0
+	for _, o := range origins {
+		if o == "*" {
+			allowAll = true
+		}
+	}
+	return func(r *http.Request) bool {
+		if allowAll {
+			return true
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		for _, o := range origins {
+			if matchOrigin(o, origin, u.Host) {
+				return true
+			}
+		}
+		logs.Warn("reject websocket origin:%s", origin)
+		return false
+	}
+}
+
+func matchOrigin(pattern, origin, host string) bool {
+	if strings.Contains(pattern, "://") {
+		return strings.EqualFold(pattern, origin)
+	}
+	host = strings.ToLower(host)
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,74 @@
+package net
+
+import (
+	"common/biz"
+	"common/logs"
+	"framework/protocol"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRPCTimeout       = 5 * time.Second
+	defaultMaxRunRoutineNum = 1024
+)
+
+type pendingKey struct {
+	cid string
+	id  uint
+}
+
+// pendingRequests 转发给后端还没有回复的请求 后端宕机或者serverId已经失效时 nats发送也会成功
+// 超时后回复客户端RequestTimeout 不再计入处理中的请求 下线时不会一直等到deadline
+type pendingRequests struct {
+	sync.Mutex
+	timers map[pendingKey]*time.Timer
+}
+
+func (m *Manager) rpcTimeout() time.Duration {
+	if m.RPCTimeout <= 0 {
+		return defaultRPCTimeout
+	}
+	return m.RPCTimeout
+}
+
+// addPending 转发请求前登记 同一个连接重复的消息id替换之前的
+func (m *Manager) addPending(cid string, message *protocol.Message) pendingKey {
+	key := pendingKey{cid: cid, id: message.ID}
+	timer := time.AfterFunc(m.rpcTimeout(), func() {
+		if !m.donePending(key) {
+			return
+		}
+		logs.Warn("connector request timeout, cid=%s, route=%s, timeout=%v", cid, message.Route, m.rpcTimeout())
+		//断线恢复后连接会换成新的 按cid重新查找
+		if c, ok := m.getClient(cid); ok {
+			m.response(c, message, nil, biz.RequestTimeout)
+		}
+	})
+	m.pending.Lock()
+	old, ok := m.pending.timers[key]
+	m.pending.timers[key] = timer
+	m.pending.Unlock()
+	if ok {
+		old.Stop()
+	} else {
+		m.inflight.Add(1)
+	}
+	return key
+}
+
+// donePending 收到回复或者超时 只有先到的一方返回true
+func (m *Manager) donePending(key pendingKey) bool {
+	m.pending.Lock()
+	timer, ok := m.pending.timers[key]
+	if ok {
+		delete(m.pending.timers, key)
+	}
+	m.pending.Unlock()
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	m.inflight.Add(-1)
+	return true
+}
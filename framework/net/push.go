@@ -0,0 +1,206 @@
+package net
+
+import (
+	"common/logs"
+	"fmt"
+	"framework/protocol"
+	"framework/remote"
+	"framework/serializer"
+)
+
+// pushBody 推送的数据 按每个连接协商的序列化方式编码 同一种方式只编码一次
+type pushBody struct {
+	data   any
+	bodies map[string][]byte
+	errs   map[string]error
+}
+
+func newPushBody(data any) *pushBody {
+	return &pushBody{
+		data:   data,
+		bodies: make(map[string][]byte),
+		errs:   make(map[string]error),
+	}
+}
+
+// encodedPushBody 后端服务器已经编码好的推送 没有的序列化方式推送失败
+func encodedPushBody(bodies map[string][]byte) *pushBody {
+	b := newPushBody(nil)
+	for name, data := range bodies {
+		b.bodies[name] = data
+	}
+	return b
+}
+
+func (b *pushBody) encode(s serializer.Serializer) ([]byte, error) {
+	name := s.Name()
+	if data, ok := b.bodies[name]; ok {
+		return data, nil
+	}
+	if err, ok := b.errs[name]; ok {
+		return nil, err
+	}
+	var data []byte
+	var err error
+	if b.data == nil {
+		err = fmt.Errorf("push body not encoded with %s", name)
+	} else {
+		data, err = s.Marshal(b.data)
+	}
+	if err != nil {
+		logs.Error("push marshal data with %s err:%v", name, err)
+		b.errs[name] = err
+		return nil, err
+	}
+	b.bodies[name] = data
+	return data, nil
+}
+
+// PushToUsers 推送消息给指定的用户 返回不在线的uid
+func (m *Manager) PushToUsers(route string, data any, uids []string) []string {
+	return m.pushToUsers(route, newPushBody(data), uids)
+}
+
+// PushToCids 推送消息给指定的连接 返回不在线的cid
+func (m *Manager) PushToCids(route string, data any, cids []string) []string {
+	return m.pushToCids(route, newPushBody(data), cids)
+}
+
+// PushToAll 推送给本connector上的所有连接 包括还没有认证的 返回失败的cid
+func (m *Manager) PushToAll(route string, data any) []string {
+	body := newPushBody(data)
+	var failed []string
+	for _, c := range m.allClients() {
+		if err := m.pushNoWait(c, route, body); err != nil {
+			failed = append(failed, c.GetSession().Cid())
+		}
+	}
+	return failed
+}
+
+// PushToServers 按connector分组推送 本connector上的直接推送 其他的转发给对应的connector
+// 转发的推送不等待回复 失败的目标由对方connector记录日志
+func (m *Manager) PushToServers(route string, data any, uidsByServer map[string][]string) ([]string, error) {
+	bodies, err := serializer.MarshalAll(data)
+	if err != nil {
+		var failed []string
+		for _, uids := range uidsByServer {
+			failed = append(failed, uids...)
+		}
+		return failed, err
+	}
+	var failed []string
+	for serverId, uids := range uidsByServer {
+		if serverId == m.ServerId {
+			failed = append(failed, m.pushToUsers(route, encodedPushBody(bodies), uids)...)
+			continue
+		}
+		msg := &remote.Msg{
+			Type: remote.PushMsg,
+			Src:  m.ServerId,
+			Dst:  serverId,
+			Uids: uids,
+			Body: &protocol.Message{
+				Type:  protocol.Push,
+				Route: route,
+			},
+			Bodies: bodies,
+		}
+		buf, err := msg.Encode()
+		if err == nil && m.RemoteCli != nil {
+			err = m.RemoteCli.SendMsg(serverId, buf)
+		}
+		if err != nil || m.RemoteCli == nil {
+			logs.Error("push to connector[%s] err:%v", serverId, err)
+			failed = append(failed, uids...)
+		}
+	}
+	return failed, nil
+}
+
+// pushToUsers 推送给多个用户时不等待写队列 一个慢连接不会拖慢其他人
+func (m *Manager) pushToUsers(route string, body *pushBody, uids []string) []string {
+	send := m.pushNoWait
+	if len(uids) == 1 {
+		send = m.push
+	}
+	var failed []string
+	for _, uid := range uids {
+		c, ok := m.getClientByUid(uid)
+		if !ok || send(c, route, body) != nil {
+			failed = append(failed, uid)
+		}
+	}
+	return failed
+}
+
+func (m *Manager) pushToCids(route string, body *pushBody, cids []string) []string {
+	send := m.pushNoWait
+	if len(cids) == 1 {
+		send = m.push
+	}
+	var failed []string
+	for _, cid := range cids {
+		c, ok := m.getClient(cid)
+		if !ok || send(c, route, body) != nil {
+			failed = append(failed, cid)
+		}
+	}
+	return failed
+}
+
+// push 推送给单个连接 写队列满时最多等待SendTimeout
+func (m *Manager) push(c Connection, route string, body *pushBody) error {
+	buf, err := encodePush(c, route, body)
+	if err != nil {
+		return err
+	}
+	return c.SendMessage(buf)
+}
+
+// pushNoWait 广播时使用 写队列满时直接按策略处理
+func (m *Manager) pushNoWait(c Connection, route string, body *pushBody) error {
+	buf, err := encodePush(c, route, body)
+	if err != nil {
+		return err
+	}
+	return sendNoWait(c, buf)
+}
+
+func encodePush(c Connection, route string, body *pushBody) ([]byte, error) {
+	data, err := body.encode(c.GetSession().Serializer())
+	if err != nil {
+		return nil, err
+	}
+	return encodeData(&protocol.Message{
+		Type:  protocol.Push,
+		Route: route,
+		Data:  data,
+	})
+}
+
+// remotePush 处理后端服务器发来的推送 推送完把失败的目标回复给发送方
+func (m *Manager) remotePush(msg *remote.Msg) {
+	if msg.Body == nil {
+		logs.Error("remote push msg without body, src=%s", msg.Src)
+		return
+	}
+	body := encodedPushBody(msg.Bodies)
+	failed := m.pushToUsers(msg.Body.Route, body, msg.Uids)
+	failed = append(failed, m.pushToCids(msg.Body.Route, body, msg.Cids)...)
+	ack := &remote.Msg{
+		Type:   remote.PushAckMsg,
+		Src:    m.ServerId,
+		Dst:    msg.Src,
+		Seq:    msg.Seq,
+		Failed: failed,
+	}
+	data, err := ack.Encode()
+	if err != nil {
+		logs.Error("encode push ack err:%v", err)
+		return
+	}
+	if err := m.RemoteCli.SendMsg(msg.Src, data); err != nil {
+		logs.Error("send push ack to %s err:%v", msg.Src, err)
+	}
+}
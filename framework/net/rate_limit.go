@@ -0,0 +1,162 @@
+package net
+
+import (
+	"common/biz"
+	"common/logs"
+	"framework/game"
+	"framework/protocol"
+	"framework/protocol/pb"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxMessageSize int64 = 1024
+	defaultMaxWarnings          = 3
+	violationWindow             = 10 * time.Second // 超过这个时间没有再违规 警告次数清零
+)
+
+// tokenBucket 令牌桶 rate为0表示不限制
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	if burst < rate {
+		burst = rate
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst}
+}
+
+// enough 按经过的时间补充令牌 返回是否足够 不扣除
+func (b *tokenBucket) enough(n float64, now time.Time) bool {
+	if b == nil || b.rate <= 0 {
+		return true
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	return b.tokens >= n
+}
+
+func (b *tokenBucket) take(n float64, now time.Time) bool {
+	if !b.enough(n, now) {
+		return false
+	}
+	if b != nil && b.rate > 0 {
+		b.tokens -= n
+	}
+	return true
+}
+
+// rateLimiter 每个连接一个 限制消息数、字节数以及单个路由的频率
+type rateLimiter struct {
+	sync.Mutex
+	maxWarnings   int
+	msg           *tokenBucket
+	bytes         *tokenBucket
+	routes        map[string]*tokenBucket
+	violations    int
+	lastViolation time.Time
+}
+
+// newRateLimiter 字节数的突发至少是一条消息的最大长度 否则大于bytesPerSecond的消息永远发不出去
+func newRateLimiter(conf game.RateLimitConfig, maxMessageSize int64) *rateLimiter {
+	l := &rateLimiter{
+		maxWarnings: conf.MaxWarnings,
+		msg:         newTokenBucket(conf.MsgPerSecond, conf.Burst),
+		bytes:       newTokenBucket(conf.BytesPerSecond, max(conf.BytesPerSecond, float64(maxMessageSize))),
+		routes:      make(map[string]*tokenBucket, len(conf.Routes)),
+	}
+	if l.maxWarnings <= 0 {
+		l.maxWarnings = defaultMaxWarnings
+	}
+	for _, r := range conf.Routes {
+		l.routes[r.Route] = newTokenBucket(r.MsgPerSecond, r.Burst)
+	}
+	return l
+}
+
+// allow 单独配置了限制的路由使用自己的消息数限制 代替连接的消息数限制 字节数按连接限制
+// 消息数和字节数都满足时才扣除 被丢弃的消息不消耗额度
+func (l *rateLimiter) allow(route string, size int) bool {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	msg := l.msg
+	if b, ok := l.routes[route]; ok {
+		msg = b
+	}
+	if !msg.enough(1, now) || !l.bytes.enough(float64(size), now) {
+		return false
+	}
+	msg.take(1, now)
+	l.bytes.take(float64(size), now)
+	return true
+}
+
+// violate 记录一次违规 返回还剩多少次警告 小于0时应该踢下线
+func (l *rateLimiter) violate() int {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastViolation) > violationWindow {
+		l.violations = 0
+	}
+	l.lastViolation = now
+	l.violations++
+	return l.maxWarnings - l.violations
+}
+
+// limitedConn 带发送频率限制的连接
+type limitedConn interface {
+	limiter() *rateLimiter
+}
+
+// checkRate 超过频率限制的消息直接丢弃 先警告 多次违规后踢下线
+func (m *Manager) checkRate(c Connection, message *protocol.Message, size int) bool {
+	lc, ok := c.(limitedConn)
+	if !ok || lc.limiter() == nil {
+		return true
+	}
+	l := lc.limiter()
+	if l.allow(message.Route, size) {
+		return true
+	}
+	remain := l.violate()
+	session := c.GetSession()
+	logs.Warn("client[%s] uid=%s rate limited, route=%s, remain=%d", session.Cid(), session.Uid(), message.Route, remain)
+	if remain < 0 {
+		m.Kick(c, protocol.KickBody{
+			Code:   protocol.KickRateLimited,
+			Reason: biz.RequestTooFrequent.Error(),
+		})
+		return false
+	}
+	m.warn(c, protocol.WarningBody{
+		Code:   protocol.KickRateLimited,
+		Reason: biz.RequestTooFrequent.Error(),
+		Remain: remain,
+	})
+	m.response(c, message, nil, biz.RequestTooFrequent)
+	return false
+}
+
+// warn 推送协议层的警告
+func (m *Manager) warn(c Connection, body protocol.WarningBody) {
+	err := m.push(c, protocol.WarningRoute, newPushBody(&pb.WarningBody{
+		Code:   int32(body.Code),
+		Reason: body.Reason,
+		Remain: int32(body.Remain),
+	}))
+	if err != nil {
+		logs.Error("send warning err:%v", err)
+	}
+}
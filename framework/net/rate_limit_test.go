@@ -0,0 +1,102 @@
+package net
+
+import (
+	"framework/game"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name  string
+		rate  float64
+		burst float64
+		takes []time.Duration // 每次取令牌距离start的时间
+		want  []bool
+	}{
+		{name: "unlimited", rate: 0, takes: []time.Duration{0, 0, 0}, want: []bool{true, true, true}},
+		{name: "burst then refuse", rate: 1, burst: 2, takes: []time.Duration{0, 0, 0}, want: []bool{true, true, false}},
+		{name: "refill by elapsed time", rate: 2, burst: 2, takes: []time.Duration{0, 0, 0, 500 * time.Millisecond}, want: []bool{true, true, false, true}},
+		{name: "refill never exceeds burst", rate: 1, burst: 1, takes: []time.Duration{0, 10 * time.Second, 10 * time.Second}, want: []bool{true, true, false}},
+		{name: "burst at least rate", rate: 3, burst: 1, takes: []time.Duration{0, 0, 0, 0}, want: []bool{true, true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.rate, tt.burst)
+			for i, d := range tt.takes {
+				if got := b.take(1, start.Add(d)); got != tt.want[i] {
+					t.Fatalf("take %d at %v = %t, want %t", i, d, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	type send struct {
+		route string
+		size  int
+		want  bool
+	}
+	tests := []struct {
+		name    string
+		conf    game.RateLimitConfig
+		maxSize int64
+		sends   []send
+	}{
+		{
+			name:  "message limit",
+			conf:  game.RateLimitConfig{MsgPerSecond: 2},
+			sends: []send{{"a.b.c", 1, true}, {"a.b.c", 1, true}, {"a.b.c", 1, false}},
+		},
+		{
+			name: "route limit replaces message limit",
+			conf: game.RateLimitConfig{MsgPerSecond: 1, Routes: []game.RouteLimitConfig{{Route: "game.h.move", MsgPerSecond: 3}}},
+			sends: []send{
+				{"game.h.move", 1, true}, {"game.h.move", 1, true}, {"game.h.move", 1, true}, {"game.h.move", 1, false},
+				{"hall.h.info", 1, true}, {"hall.h.info", 1, false},
+			},
+		},
+		{
+			name:  "dropped message does not charge bytes",
+			conf:  game.RateLimitConfig{MsgPerSecond: 1, BytesPerSecond: 10},
+			sends: []send{{"a.b.c", 6, true}, {"a.b.c", 6, false}},
+		},
+		{
+			name:  "too many bytes does not charge message",
+			conf:  game.RateLimitConfig{MsgPerSecond: 2, BytesPerSecond: 10},
+			sends: []send{{"a.b.c", 8, true}, {"a.b.c", 8, false}, {"a.b.c", 2, true}},
+		},
+		{
+			name:    "message larger than bytes per second",
+			conf:    game.RateLimitConfig{BytesPerSecond: 100},
+			maxSize: 1024,
+			sends:   []send{{"a.b.c", 1000, true}, {"a.b.c", 100, false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.conf, tt.maxSize)
+			for i, s := range tt.sends {
+				if got := l.allow(s.route, s.size); got != s.want {
+					t.Fatalf("send %d %s(%d bytes) = %t, want %t", i, s.route, s.size, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterViolate(t *testing.T) {
+	l := newRateLimiter(game.RateLimitConfig{MaxWarnings: 2}, 0)
+	for i, want := range []int{1, 0, -1} {
+		if got := l.violate(); got != want {
+			t.Fatalf("violate %d remain = %d, want %d", i, got, want)
+		}
+	}
+	//超过时间窗口没有再违规 重新计数
+	l.lastViolation = time.Now().Add(-violationWindow - time.Second)
+	if got := l.violate(); got != 1 {
+		t.Fatalf("violate after window remain = %d, want 1", got)
+	}
+}
@@ -0,0 +1,144 @@
+package net
+
+import (
+	"common/logs"
+	"context"
+	"framework/protocol"
+	"framework/remote"
+	"time"
+)
+
+const (
+	registryTimeout = 3 * time.Second
+	//记录的过期时间 连接期间定时续期 connector崩溃后记录会自动过期
+	registryTTL             = time.Minute
+	registryRefreshInterval = registryTTL / 3
+)
+
+// UserRegistry 记录用户当前所在的connector和连接 多个connector共享 例如使用redis实现
+type UserRegistry interface {
+	// Bind 记录新的连接 ttl后过期 返回之前记录的connector和连接
+	Bind(ctx context.Context, uid, connectorId, cid string, ttl time.Duration) (oldConnectorId, oldCid string, err error)
+	// Unbind 连接断开时删除 只有记录的还是这个连接时才删除
+	Unbind(ctx context.Context, uid, connectorId, cid string) error
+	// Refresh 给本connector上的连接续期 users: uid -> cid 只续期记录的还是这个连接的
+	Refresh(ctx context.Context, connectorId string, users map[string]string, ttl time.Duration) error
+}
+
+var duplicatedKick = protocol.KickBody{
+	Code:   protocol.KickDuplicated,
+	Reason: "account login on another device",
+}
+
+// kickDuplicated 同一个用户在本connector上已经有其他连接 踢掉旧的连接
+func (m *Manager) kickDuplicated(uid, cid string) {
+	old, ok := m.getClientByUid(uid)
+	if !ok || old.GetSession().Cid() == cid {
+		return
+	}
+	logs.Info("user[%s] login again, kick old client[%s]", uid, old.GetSession().Cid())
+	m.Kick(old, duplicatedKick)
+}
+
+// registerUser 在共享的注册表中记录用户 旧的连接在其他connector上时通知对方踢掉
+// 在单独的协程中执行 大量用户同时登录时不阻塞消息的分发
+func (m *Manager) registerUser(uid, cid string) {
+	if m.Registry == nil {
+		return
+	}
+	go m.bindRegistry(uid, cid)
+}
+
+func (m *Manager) bindRegistry(uid, cid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+	oldConnectorId, oldCid, err := m.Registry.Bind(ctx, uid, m.ServerId, cid, registryTTL)
+	if err != nil {
+		logs.Error("registry bind user[%s] err:%v", uid, err)
+		return
+	}
+	//记录之前连接已经断开 断开时的Unbind可能先执行了
+	if c, ok := m.getClientByUid(uid); !ok || c.GetSession().Cid() != cid {
+		if err := m.Registry.Unbind(ctx, uid, m.ServerId, cid); err != nil {
+			logs.Error("registry unbind user[%s] err:%v", uid, err)
+		}
+	}
+	if oldCid == "" || oldCid == cid {
+		return
+	}
+	//本connector上的旧连接在kickDuplicated中已经处理
+	if oldConnectorId == m.ServerId {
+		return
+	}
+	m.sendKick(oldConnectorId, uid, oldCid)
+}
+
+func (m *Manager) unregisterUser(uid, cid string) {
+	if m.Registry == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+	if err := m.Registry.Unbind(ctx, uid, m.ServerId, cid); err != nil {
+		logs.Error("registry unbind user[%s] err:%v", uid, err)
+	}
+}
+
+// refreshRegistry 定时给本connector上所有在线用户续期
+func (m *Manager) refreshRegistry() {
+	ticker := time.NewTicker(registryRefreshInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.RLock()
+		users := make(map[string]string, len(m.users))
+		for uid, cid := range m.users {
+			users[uid] = cid
+		}
+		m.RUnlock()
+		if len(users) == 0 {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+		if err := m.Registry.Refresh(ctx, m.ServerId, users, registryTTL); err != nil {
+			logs.Error("registry refresh %d users err:%v", len(users), err)
+		}
+		cancel()
+	}
+}
+
+func (m *Manager) sendKick(connectorId, uid, cid string) {
+	if m.RemoteCli == nil {
+		return
+	}
+	kick := duplicatedKick
+	msg := &remote.Msg{
+		Type: remote.KickMsg,
+		Cid:  cid,
+		Uid:  uid,
+		Src:  m.ServerId,
+		Dst:  connectorId,
+		Kick: &kick,
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		logs.Error("encode kick msg err:%v", err)
+		return
+	}
+	if err := m.RemoteCli.SendMsg(connectorId, data); err != nil {
+		logs.Error("send kick msg to %s err:%v", connectorId, err)
+	}
+}
+
+// remoteKick 其他connector通知踢掉本connector上的连接
+func (m *Manager) remoteKick(msg *remote.Msg) {
+	c, ok := m.getClient(msg.Cid)
+	if !ok {
+		return
+	}
+	logs.Info("user[%s] kicked by %s, client[%s]", msg.Uid, msg.Src, msg.Cid)
+	body := duplicatedKick
+	if msg.Kick != nil {
+		body = *msg.Kick
+	}
+	m.Kick(c, body)
+}
@@ -0,0 +1,188 @@
+package net
+
+import (
+	"common/logs"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const defaultResumeBuffer = 128
+
+// suspendedConn 网络断开后在宽限期内保留的session 发给它的消息先缓存 客户端恢复后按顺序补发
+type suspendedConn struct {
+	sync.Mutex
+	manager *Manager
+	session *Session
+	token   string
+	buffer  [][]byte
+	max     int
+	timer   *time.Timer
+	target  Connection // 恢复后的新连接 之后的消息直接转发
+	expired bool
+}
+
+func (s *suspendedConn) GetSession() *Session {
+	return s.session
+}
+
+func (s *suspendedConn) SendMessage(buf []byte) error {
+	return s.send(buf, false)
+}
+
+func (s *suspendedConn) trySendMessage(buf []byte) error {
+	return s.send(buf, true)
+}
+
+// send 缓存本身不会阻塞 恢复之后转发给新连接
+func (s *suspendedConn) send(buf []byte, noWait bool) error {
+	s.Lock()
+	if target := s.target; target != nil {
+		s.Unlock()
+		if noWait {
+			return sendNoWait(target, buf)
+		}
+		return target.SendMessage(buf)
+	}
+	if s.expired {
+		s.Unlock()
+		return ErrConnectionClosed
+	}
+	if len(s.buffer) >= s.max {
+		//缓存满了之后无法保证补发完整 直接让session过期 客户端重新登录
+		s.Unlock()
+		logs.Warn("client[%s] resume buffer full, expire session", s.session.Cid())
+		s.manager.expire(s)
+		return ErrQueueFull
+	}
+	s.buffer = append(s.buffer, buf)
+	s.Unlock()
+	return nil
+}
+
+// Close 例如被重复登录踢下线 不再等待恢复
+func (s *suspendedConn) Close() {
+	s.manager.expire(s)
+}
+
+// handshaker 恢复的连接握手回复之后才补发断线期间的消息 保证客户端先拿到路由字典
+type handshaker interface {
+	sendHandshake(buf []byte) error
+}
+
+// resumedConn 恢复的连接 resumeClient中标记 只在之后的第一次握手返回true
+type resumedConn interface {
+	takeResumed() bool
+}
+
+// issueResumeToken 握手时下发新的恢复凭证 返回是否是恢复的连接
+func (m *Manager) issueResumeToken(c Connection) (string, bool) {
+	if m.ResumeGrace <= 0 {
+		return "", false
+	}
+	token := uuid.NewString()
+	c.GetSession().setResumeToken(token)
+	rc, ok := c.(resumedConn)
+	return token, ok && rc.takeResumed()
+}
+
+// suspend 非正常断开的已登录连接 在宽限期内保留session 返回false表示直接清理
+func (m *Manager) suspend(conn Connection) bool {
+	session := conn.GetSession()
+	token := session.ResumeToken()
+	if m.ResumeGrace <= 0 || token == "" || session.Uid() == "" {
+		return false
+	}
+	s := &suspendedConn{
+		manager: m,
+		session: session,
+		token:   token,
+		max:     m.resumeBuffer(),
+	}
+	m.Lock()
+	cid := session.Cid()
+	if m.clients[cid] != conn {
+		m.Unlock()
+		return false
+	}
+	s.Lock()
+	s.timer = time.AfterFunc(m.ResumeGrace, func() {
+		logs.Info("client[%s] resume grace expired", s.session.Cid())
+		m.expire(s)
+	})
+	s.Unlock()
+	m.clients[cid] = s
+	m.resumes[token] = s
+	m.Unlock()
+	logs.Info("client[%s] uid=%s suspended, wait %v for resume", cid, session.Uid(), m.ResumeGrace)
+	return true
+}
+
+// expire 宽限期到了或者被关闭 这时才算真正下线 通知后端服务器
+func (m *Manager) expire(s *suspendedConn) {
+	s.Lock()
+	if s.target != nil || s.expired {
+		s.Unlock()
+		return
+	}
+	s.expired = true
+	s.buffer = nil
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.Unlock()
+	cid := s.session.Cid()
+	m.Lock()
+	if m.resumes[s.token] == s {
+		delete(m.resumes, s.token)
+	}
+	if c, ok := m.clients[cid]; ok && c == Connection(s) {
+		delete(m.clients, cid)
+		if uid := s.session.Uid(); uid != "" && m.users[uid] == cid {
+			delete(m.users, uid)
+		}
+	}
+	m.Unlock()
+	m.sessionClosed(s.session)
+}
+
+// resumeClient 用恢复凭证找回session 新连接沿用原来的cid 补发断开期间缓存的消息
+// 凭证无效或已经过期时返回nil 按新连接处理
+func (m *Manager) resumeClient(wsConn *websocket.Conn, token string) *WsConnection {
+	m.Lock()
+	s, ok := m.resumes[token]
+	delete(m.resumes, token)
+	m.Unlock()
+	if !ok {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.expired {
+		return nil
+	}
+	s.timer.Stop()
+	client := newWsConnection(wsConn, m, s.session)
+	client.resumed.Store(true)
+	//缓存的消息放到新连接的暂存中 握手回复之后按顺序发出 之后的消息排在后面
+	client.queue.hold(s.buffer)
+	logs.Info("client[%s] uid=%s resumed, replay %d messages", client.Cid, s.session.Uid(), len(s.buffer))
+	s.buffer = nil
+	s.target = client
+	m.Lock()
+	m.clients[client.Cid] = client
+	if uid := s.session.Uid(); uid != "" {
+		m.users[uid] = client.Cid
+	}
+	m.Unlock()
+	return client
+}
+
+func (m *Manager) resumeBuffer() int {
+	if m.ResumeBuffer <= 0 {
+		return defaultResumeBuffer
+	}
+	return m.ResumeBuffer
+}
@@ -0,0 +1,193 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestConn(m *Manager, cid string) *WsConnection {
+	return newWsConnection(nil, m, NewSession(cid, m.ServerId))
+}
+
+// suspendedClient 注册一个已登录的连接 然后模拟网络断开
+func suspendedClient(t *testing.T, m *Manager, cid, uid string) (*WsConnection, string) {
+	t.Helper()
+	c := newTestConn(m, cid)
+	c.Session.Bind(uid)
+	m.clients[cid] = c
+	m.users[uid] = cid
+	token, _ := m.issueResumeToken(c)
+	m.removeClient(c, true)
+	return c, token
+}
+
+func TestIssueResumeToken(t *testing.T) {
+	m := NewManager()
+	resumed := newTestConn(m, "c2")
+	resumed.resumed.Store(true)
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		conn      Connection
+		wantToken bool
+		resumed   bool
+	}{
+		{name: "resume disabled", conn: newTestConn(m, "c1")},
+		{name: "new connection", grace: time.Second, conn: newTestConn(m, "c1"), wantToken: true},
+		{name: "resumed connection", grace: time.Second, conn: resumed, wantToken: true, resumed: true},
+		{name: "resumed only once", grace: time.Second, conn: resumed, wantToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.ResumeGrace = tt.grace
+			token, ok := m.issueResumeToken(tt.conn)
+			if (token != "") != tt.wantToken || ok != tt.resumed {
+				t.Fatalf("issueResumeToken = %q, %t, want token %t, resumed %t", token, ok, tt.wantToken, tt.resumed)
+			}
+			if got := tt.conn.GetSession().ResumeToken(); got != token {
+				t.Fatalf("session token = %q, want %q", got, token)
+			}
+		})
+	}
+}
+
+func TestSuspend(t *testing.T) {
+	tests := []struct {
+		name       string
+		grace      time.Duration
+		uid        string
+		token      bool
+		registered bool
+		want       bool
+	}{
+		{name: "resume disabled", uid: "u1", token: true, registered: true},
+		{name: "no token", grace: time.Second, uid: "u1", registered: true},
+		{name: "not logged in", grace: time.Second, token: true, registered: true},
+		{name: "replaced connection", grace: time.Second, uid: "u1", token: true},
+		{name: "suspended", grace: time.Second, uid: "u1", token: true, registered: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			m.ResumeGrace = tt.grace
+			c := newTestConn(m, "c1")
+			c.Session.Bind(tt.uid)
+			if tt.token {
+				c.Session.setResumeToken("token")
+			}
+			if tt.registered {
+				m.clients["c1"] = c
+			}
+			if got := m.suspend(c); got != tt.want {
+				t.Fatalf("suspend = %t, want %t", got, tt.want)
+			}
+			c1, _ := m.getClient("c1")
+			if _, ok := c1.(*suspendedConn); ok != tt.want {
+				t.Fatalf("client suspended = %t, want %t", ok, tt.want)
+			}
+			if s, ok := m.resumes["token"]; ok {
+				m.expire(s)
+			}
+		})
+	}
+}
+
+func TestSuspendedConn(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		sends   int
+		wait    time.Duration
+		err     error
+		expired bool
+	}{
+		{name: "buffer messages", grace: time.Second, sends: 2},
+		{name: "buffer full", grace: time.Second, sends: 3, err: ErrQueueFull, expired: true},
+		{name: "grace expired", grace: 20 * time.Millisecond, wait: 100 * time.Millisecond, sends: 1, err: ErrConnectionClosed, expired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			m.ResumeGrace = tt.grace
+			m.ResumeBuffer = 2
+			c, token := suspendedClient(t, m, "c1", "u1")
+			if !c.closed() {
+				t.Fatal("old connection not closed")
+			}
+			s, _ := m.getClient("c1")
+			time.Sleep(tt.wait)
+			var err error
+			for i := 0; i < tt.sends; i++ {
+				err = s.SendMessage([]byte{byte(i)})
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("send err = %v, want %v", err, tt.err)
+			}
+			m.RLock()
+			_, online := m.users["u1"]
+			_, resumable := m.resumes[token]
+			m.RUnlock()
+			if online == tt.expired || resumable == tt.expired {
+				t.Fatalf("online = %t, resumable = %t, want expired %t", online, resumable, tt.expired)
+			}
+		})
+	}
+}
+
+func TestResumeClient(t *testing.T) {
+	m := NewManager()
+	m.ResumeGrace = time.Second
+	_, token := suspendedClient(t, m, "c1", "u1")
+	s, _ := m.getClient("c1")
+	for _, buf := range []string{"m1", "m2"} {
+		if err := s.SendMessage([]byte(buf)); err != nil {
+			t.Fatalf("send %s err = %v", buf, err)
+		}
+	}
+
+	if m.resumeClient(nil, "unknown") != nil {
+		t.Fatal("resumed with unknown token")
+	}
+	client := m.resumeClient(nil, token)
+	if client == nil {
+		t.Fatal("resume failed")
+	}
+	if m.resumeClient(nil, token) != nil {
+		t.Fatal("token used twice")
+	}
+	if client.Cid != "c1" || client.Session.Uid() != "u1" {
+		t.Fatalf("resumed cid = %s uid = %s, want c1 u1", client.Cid, client.Session.Uid())
+	}
+	if c, _ := m.getClientByUid("u1"); c != Connection(client) {
+		t.Fatal("resumed connection not registered")
+	}
+	//握手之前的消息排在缓存的消息后面
+	if err := s.SendMessage([]byte("m3")); err != nil {
+		t.Fatalf("send after resume err = %v", err)
+	}
+	if len(client.WriteChan) != 0 {
+		t.Fatalf("sent %d messages before handshake", len(client.WriteChan))
+	}
+	if err := client.sendHandshake([]byte("hs")); err != nil {
+		t.Fatalf("handshake err = %v", err)
+	}
+	var got []string
+	for len(client.WriteChan) > 0 {
+		got = append(got, string(<-client.WriteChan))
+	}
+	want := []string{"hs", "m1", "m2", "m3"}
+	if len(got) != len(want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sent %v, want %v", got, want)
+		}
+	}
+	//恢复之后过期不影响新连接
+	m.expire(s.(*suspendedConn))
+	if c, _ := m.getClient("c1"); c != Connection(client) {
+		t.Fatal("expire removed the resumed connection")
+	}
+}
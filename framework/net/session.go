@@ -0,0 +1,250 @@
+package net
+
+import (
+	"context"
+	"framework/remote"
+	"framework/serializer"
+	"sync"
+)
+
+// Session 每个连接对应一个session 保存连接绑定的用户、后端服务器以及自定义数据
+// handler中可以读写session 转发给后端服务器时会带上session的数据
+// WithContext得到的session和原来的共用数据 只有上下文不同
+type Session struct {
+	*sessionState
+	ctx context.Context
+}
+
+type sessionState struct {
+	sync.RWMutex
+	cid         string
+	connectorId string // 连接所在的connector
+	uid         string
+	servers     map[string]string // serverType -> serverId 用户绑定的后端服务器
+	data        map[string]any
+	resumeToken string // 断线重连时用来找回session
+	serializer  string // 握手时协商的序列化方式 转发给后端服务器时一起带上
+	//创建之后修改过的key 后端服务器回复时只同步这些
+	uidChanged     bool
+	changedData    map[string]bool
+	changedServers map[string]bool
+}
+
+func NewSession(cid, connectorId string) *Session {
+	return &Session{sessionState: &sessionState{
+		cid:            cid,
+		connectorId:    connectorId,
+		servers:        make(map[string]string),
+		data:           make(map[string]any),
+		changedData:    make(map[string]bool),
+		changedServers: make(map[string]bool),
+	}}
+}
+
+func (s *Session) Cid() string {
+	return s.cid
+}
+
+func (s *Session) ConnectorId() string {
+	return s.connectorId
+}
+
+func (s *Session) Uid() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.uid
+}
+
+func (s *Session) ResumeToken() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.resumeToken
+}
+
+func (s *Session) setResumeToken(token string) {
+	s.Lock()
+	defer s.Unlock()
+	s.resumeToken = token
+}
+
+// Serializer 消息体的序列化方式 没有协商过的使用json
+func (s *Session) Serializer() serializer.Serializer {
+	s.RLock()
+	name := s.serializer
+	s.RUnlock()
+	if sr, ok := serializer.Get(name); ok {
+		return sr
+	}
+	return serializer.Default()
+}
+
+func (s *Session) SetSerializer(name string) {
+	s.Lock()
+	defer s.Unlock()
+	s.serializer = name
+}
+
+// Context handler执行的上下文 带有HandleTimeOut的超时 访问数据库等操作应该使用它
+func (s *Session) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// WithContext 每次调用handler时使用 同一个连接上并发的handler各自有自己的上下文
+func (s *Session) WithContext(ctx context.Context) *Session {
+	return &Session{sessionState: s.sessionState, ctx: ctx}
+}
+
+// Bind 绑定用户 认证通过后调用
+func (s *Session) Bind(uid string) {
+	s.Lock()
+	defer s.Unlock()
+	s.uid = uid
+	s.uidChanged = true
+}
+
+// BindServer 绑定某一类型的后端服务器 例如用户进入的game服务器
+func (s *Session) BindServer(serverType, serverId string) {
+	s.Lock()
+	defer s.Unlock()
+	s.servers[serverType] = serverId
+	s.changedServers[serverType] = true
+}
+
+func (s *Session) UnbindServer(serverType string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.servers, serverType)
+	s.changedServers[serverType] = true
+}
+
+func (s *Session) GetServer(serverType string) (string, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	serverId, ok := s.servers[serverType]
+	return serverId, ok
+}
+
+// Servers 返回绑定服务器的拷贝
+func (s *Session) Servers() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	servers := make(map[string]string, len(s.servers))
+	for k, v := range s.servers {
+		servers[k] = v
+	}
+	return servers
+}
+
+func (s *Session) Put(key string, value any) {
+	s.Lock()
+	defer s.Unlock()
+	s.data[key] = value
+	s.changedData[key] = true
+}
+
+func (s *Session) Get(key string) (any, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *Session) Remove(key string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.data, key)
+	s.changedData[key] = true
+}
+
+// Data 返回自定义数据的拷贝 用于同步给后端服务器
+func (s *Session) Data() map[string]any {
+	s.RLock()
+	defer s.RUnlock()
+	data := make(map[string]any, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return data
+}
+
+// SetData 用转发过来的数据还原session 不算作修改
+func (s *Session) SetData(uid string, data map[string]any, servers map[string]string) {
+	s.Lock()
+	defer s.Unlock()
+	if uid != "" {
+		s.uid = uid
+	}
+	if data != nil {
+		s.data = make(map[string]any, len(data))
+		for k, v := range data {
+			s.data[k] = v
+		}
+	}
+	if servers != nil {
+		s.servers = make(map[string]string, len(servers))
+		for k, v := range servers {
+			s.servers[k] = v
+		}
+	}
+}
+
+// Changes 创建之后的修改 没有修改时返回nil
+func (s *Session) Changes() *remote.SessionChanges {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.uidChanged && len(s.changedData) == 0 && len(s.changedServers) == 0 {
+		return nil
+	}
+	c := &remote.SessionChanges{}
+	if s.uidChanged {
+		c.Uid = s.uid
+	}
+	for k := range s.changedData {
+		if v, ok := s.data[k]; ok {
+			if c.Data == nil {
+				c.Data = make(map[string]any)
+			}
+			c.Data[k] = v
+		} else {
+			c.Removed = append(c.Removed, k)
+		}
+	}
+	for k := range s.changedServers {
+		if v, ok := s.servers[k]; ok {
+			if c.Servers == nil {
+				c.Servers = make(map[string]string)
+			}
+			c.Servers[k] = v
+		} else {
+			c.UnboundServers = append(c.UnboundServers, k)
+		}
+	}
+	return c
+}
+
+// ApplyChanges 合并后端服务器同步回来的修改 没有修改的key保持不变
+func (s *Session) ApplyChanges(c *remote.SessionChanges) {
+	if c == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if c.Uid != "" {
+		s.uid = c.Uid
+	}
+	for k, v := range c.Data {
+		s.data[k] = v
+	}
+	for _, k := range c.Removed {
+		delete(s.data, k)
+	}
+	for k, v := range c.Servers {
+		s.servers[k] = v
+	}
+	for _, k := range c.UnboundServers {
+		delete(s.servers, k)
+	}
+}
@@ -0,0 +1,180 @@
+package net
+
+import (
+	"bufio"
+	"common/logs"
+	"errors"
+	"fmt"
+	"framework/protocol"
+	"io"
+	gonet "net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TcpConnection 原生客户端使用的tcp连接 和websocket使用同样的包格式
+// tcp没有ping pong 客户端需要按握手下发的间隔发送心跳 收到任何包都会延长读超时 超时时间是两倍心跳间隔
+// tcp连接暂不支持断线恢复
+type TcpConnection struct {
+	Cid       string
+	Conn      gonet.Conn
+	manager   *Manager
+	ReadChan  chan *MsgPack
+	WriteChan chan []byte
+	queue     *sendQueue
+	rl        *rateLimiter
+	closeChan chan struct{}
+	closeOnce sync.Once
+	Session   *Session
+}
+
+func (c *TcpConnection) GetSession() *Session {
+	return c.Session
+}
+
+// SendMessage 放入写队列 队列满时按配置的策略处理 返回的错误表示消息没有发出去
+func (c *TcpConnection) SendMessage(buf []byte) error {
+	return c.queue.push(buf)
+}
+
+func (c *TcpConnection) trySendMessage(buf []byte) error {
+	return c.queue.tryPush(buf)
+}
+
+func (c *TcpConnection) sendHandshake(buf []byte) error {
+	return c.queue.release(buf)
+}
+
+func (c *TcpConnection) Stats() ConnStats {
+	return c.queue.stats(c.Cid)
+}
+
+func (c *TcpConnection) limiter() *rateLimiter {
+	return c.rl
+}
+
+// abort 慢连接不再等待队列写完 直接断开
+func (c *TcpConnection) abort() {
+	logSlowConsumer(c.Cid, c.Stats())
+	c.Close()
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+}
+
+// Close 通知写协程把已经排队的消息写完再关闭连接 例如踢人的包
+func (c *TcpConnection) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+	})
+}
+
+func (c *TcpConnection) Run() {
+	go c.readMessage()
+	go c.writeMessage()
+}
+
+func (c *TcpConnection) writeMessage() {
+	defer func() {
+		if c.Conn != nil {
+			c.Conn.Close()
+		}
+	}()
+	for {
+		select {
+		case <-c.closeChan:
+			c.flush()
+			return
+		case message := <-c.WriteChan:
+			if err := c.write(message); err != nil {
+				logs.Error("client[%s] write message err :%v", c.Cid, err)
+				return
+			}
+		}
+	}
+}
+
+// flush 关闭前把队列中剩余的消息写出去
+func (c *TcpConnection) flush() {
+	for {
+		select {
+		case message := <-c.WriteChan:
+			if err := c.write(message); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *TcpConnection) write(message []byte) error {
+	//写超时需要每次重新设置
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	if _, err := c.Conn.Write(message); err != nil {
+		return err
+	}
+	c.queue.sent.Add(1)
+	return nil
+}
+
+// readMessage 按包头中的长度拆包 一个完整的包交给manager处理
+func (c *TcpConnection) readMessage() {
+	defer func() {
+		c.manager.removeClient(c, false)
+	}()
+	reader := bufio.NewReader(c.Conn)
+	maxSize := c.manager.maxMessageSize()
+	readTimeout := c.manager.tcpReadTimeout()
+	for {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			logs.Error("SetReadDeadline err:%v", err)
+			return
+		}
+		header := make([]byte, protocol.HeaderLen)
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logs.Debug("client[%s] read header err:%v", c.Cid, err)
+			}
+			return
+		}
+		length := protocol.BytesToInt(header[1:])
+		if int64(protocol.HeaderLen+length) > maxSize {
+			logs.Warn("client[%s] message too large, size=%d", c.Cid, protocol.HeaderLen+length)
+			return
+		}
+		message := make([]byte, protocol.HeaderLen+length)
+		copy(message, header)
+		if _, err := io.ReadFull(reader, message[protocol.HeaderLen:]); err != nil {
+			logs.Debug("client[%s] read body err:%v", c.Cid, err)
+			return
+		}
+		if c.ReadChan != nil {
+			c.ReadChan <- &MsgPack{
+				Cid:  c.Cid,
+				Body: message,
+			}
+		}
+	}
+}
+
+func NewTcpConnection(conn gonet.Conn, manager *Manager) *TcpConnection {
+	cid := fmt.Sprintf("%s-%s-%d", uuid.New().String(), manager.ServerId, atomic.AddUint64(&cidBase, 1))
+	c := &TcpConnection{
+		Conn:     conn,
+		manager:  manager,
+		Cid:      cid,
+		ReadChan: manager.ClientReadChan,
+		Session:  NewSession(cid, manager.ServerId),
+		rl:       newRateLimiter(manager.RateLimit, manager.maxMessageSize()),
+	}
+	c.queue = newSendQueue(manager.WriteQueue, c.abort)
+	c.WriteChan = c.queue.ch
+	c.closeChan = c.queue.closeChan
+	return c
+}
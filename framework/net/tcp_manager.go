@@ -0,0 +1,86 @@
+package net
+
+import (
+	"common/logs"
+	"crypto/tls"
+	"errors"
+	gonet "net"
+	"time"
+)
+
+// 客户端使用的传输方式
+const (
+	TransportWs   = "ws"
+	TransportTcp  = "tcp"
+	TransportBoth = "both"
+)
+
+const maxAcceptDelay = time.Second
+
+// serveTCP 监听tcp 配置了证书时使用tls
+func (m *Manager) serveTCP(addr string) {
+	listener, err := m.listenTCP(addr)
+	if err != nil {
+		logs.Fatal("connector listen tcp err:%v", err)
+	}
+	m.Lock()
+	m.tcpListener = listener
+	m.Unlock()
+	logs.Info("connector listen tcp on %s", addr)
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			//Drain时会关闭监听
+			if errors.Is(err, gonet.ErrClosed) {
+				return
+			}
+			//例如文件句柄用完 和net/http一样等一会再重试 避免空转
+			if tempDelay == 0 {
+				tempDelay = 5 * time.Millisecond
+			} else {
+				tempDelay *= 2
+			}
+			if tempDelay > maxAcceptDelay {
+				tempDelay = maxAcceptDelay
+			}
+			logs.Error("connector accept tcp err:%v, retrying in %v", err, tempDelay)
+			time.Sleep(tempDelay)
+			continue
+		}
+		tempDelay = 0
+		if m.draining.Load() {
+			conn.Close()
+			continue
+		}
+		client := NewTcpConnection(conn, m)
+		m.addClient(client)
+		m.checkAuthTimeout(client)
+		client.Run()
+	}
+}
+
+func (m *Manager) listenTCP(addr string) (gonet.Listener, error) {
+	if m.CertFile == "" || m.KeyFile == "" {
+		return gonet.Listen("tcp", addr)
+	}
+	cert, err := tls.LoadX509KeyPair(m.CertFile, m.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+// closeTCP 不再接受新的tcp连接
+func (m *Manager) closeTCP() {
+	m.Lock()
+	listener := m.tcpListener
+	m.tcpListener = nil
+	m.Unlock()
+	if listener == nil {
+		return
+	}
+	if err := listener.Close(); err != nil {
+		logs.Warn("connector %s close tcp listener err:%v", m.ServerId, err)
+	}
+}
@@ -0,0 +1,202 @@
+package net
+
+import (
+	"common/logs"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WritePolicy 写队列满了之后的处理方式
+type WritePolicy string
+
+const (
+	DropOldest WritePolicy = "dropOldest" // 丢掉队列中最早的消息 放入新消息
+	DropNewest WritePolicy = "dropNewest" // 丢掉新消息
+	Disconnect WritePolicy = "disconnect" // 断开处理不过来的连接
+)
+
+const defaultWriteQueueSize = 1024
+
+var (
+	ErrQueueFull    = errors.New("write queue full")
+	ErrSlowConsumer = errors.New("slow consumer disconnected")
+)
+
+// WriteQueueConfig 每个连接写队列的配置
+type WriteQueueConfig struct {
+	Size        int
+	Policy      WritePolicy
+	SendTimeout time.Duration // 发给单个连接时队列满了最多等待的时间 0表示不等待 广播时不等待
+}
+
+// ConnStats 连接写队列的统计
+type ConnStats struct {
+	Cid         string `json:"cid"`
+	QueueLen    int    `json:"queueLen"`
+	QueueCap    int    `json:"queueCap"`
+	MaxQueueLen int64  `json:"maxQueueLen"`
+	Sent        int64  `json:"sent"`
+	Dropped     int64  `json:"dropped"`
+}
+
+// sendQueue 带背压处理的写队列 一个客户端处理不过来不会阻塞其他人的推送
+type sendQueue struct {
+	ch        chan []byte
+	closeChan chan struct{}
+	conf      WriteQueueConfig
+	maxLen    atomic.Int64
+	sent      atomic.Int64
+	dropped   atomic.Int64
+	abort     func() //慢连接直接断开
+	holdMu    sync.Mutex
+	holding   atomic.Bool
+	held      [][]byte //恢复的连接在握手回复之前的消息 握手后再发
+}
+
+func newSendQueue(conf WriteQueueConfig, abort func()) *sendQueue {
+	if conf.Size <= 0 {
+		conf.Size = defaultWriteQueueSize
+	}
+	if conf.Policy == "" {
+		conf.Policy = Disconnect
+	}
+	return &sendQueue{
+		ch:        make(chan []byte, conf.Size),
+		closeChan: make(chan struct{}),
+		conf:      conf,
+		abort:     abort,
+	}
+}
+
+// push 队列满时最多等待SendTimeout 只用于发给单个连接的消息
+func (q *sendQueue) push(buf []byte) error {
+	return q.pushWait(buf, q.conf.SendTimeout)
+}
+
+// tryPush 不等待 队列满时直接按策略处理 广播时使用 避免一个慢连接拖慢所有人
+func (q *sendQueue) tryPush(buf []byte) error {
+	return q.pushWait(buf, 0)
+}
+
+func (q *sendQueue) pushWait(buf []byte, timeout time.Duration) error {
+	if q.holding.Load() {
+		q.holdMu.Lock()
+		if q.holding.Load() {
+			defer q.holdMu.Unlock()
+			if len(q.held) >= cap(q.ch) {
+				q.dropped.Add(1)
+				return ErrQueueFull
+			}
+			q.held = append(q.held, buf)
+			return nil
+		}
+		q.holdMu.Unlock()
+	}
+	return q.enqueue(buf, timeout)
+}
+
+// hold 之后的消息先暂存 直到release
+func (q *sendQueue) hold(bufs [][]byte) {
+	q.holdMu.Lock()
+	defer q.holdMu.Unlock()
+	q.held = append(q.held, bufs...)
+	q.holding.Store(true)
+}
+
+// release 先发first 再按顺序发暂存的消息
+func (q *sendQueue) release(first []byte) error {
+	q.holdMu.Lock()
+	defer q.holdMu.Unlock()
+	err := q.enqueue(first, q.conf.SendTimeout)
+	for _, buf := range q.held {
+		if e := q.enqueue(buf, 0); e != nil {
+			logs.Warn("release held message err:%v", e)
+		}
+	}
+	q.held = nil
+	q.holding.Store(false)
+	return err
+}
+
+func (q *sendQueue) enqueue(buf []byte, timeout time.Duration) error {
+	select {
+	case <-q.closeChan:
+		return ErrConnectionClosed
+	default:
+	}
+	select {
+	case q.ch <- buf:
+		q.enqueued()
+		return nil
+	default:
+	}
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case q.ch <- buf:
+			q.enqueued()
+			return nil
+		case <-q.closeChan:
+			return ErrConnectionClosed
+		case <-timer.C:
+		}
+	}
+	return q.full(buf)
+}
+
+// full 等待超时后队列还是满的 按策略处理
+func (q *sendQueue) full(buf []byte) error {
+	switch q.conf.Policy {
+	case DropOldest:
+		select {
+		case <-q.ch:
+			q.dropped.Add(1)
+		default:
+		}
+		select {
+		case q.ch <- buf:
+			q.enqueued()
+			return nil
+		default:
+			q.dropped.Add(1)
+			return ErrQueueFull
+		}
+	case DropNewest:
+		q.dropped.Add(1)
+		return ErrQueueFull
+	default:
+		q.dropped.Add(1)
+		if q.abort != nil {
+			q.abort()
+		}
+		return ErrSlowConsumer
+	}
+}
+
+func (q *sendQueue) enqueued() {
+	n := int64(len(q.ch))
+	for {
+		old := q.maxLen.Load()
+		if n <= old || q.maxLen.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+func (q *sendQueue) stats(cid string) ConnStats {
+	return ConnStats{
+		Cid:         cid,
+		QueueLen:    len(q.ch),
+		QueueCap:    cap(q.ch),
+		MaxQueueLen: q.maxLen.Load(),
+		Sent:        q.sent.Load(),
+		Dropped:     q.dropped.Load(),
+	}
+}
+
+func logSlowConsumer(cid string, stats ConnStats) {
+	logs.Warn("client[%s] slow consumer, queue=%d/%d dropped=%d", cid, stats.QueueLen, stats.QueueCap, stats.Dropped)
+}
@@ -0,0 +1,107 @@
+package net
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func fillQueue(t *testing.T, q *sendQueue) {
+	t.Helper()
+	for i := 0; i < cap(q.ch); i++ {
+		if err := q.push([]byte{byte(i)}); err != nil {
+			t.Fatalf("push %d err = %v", i, err)
+		}
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  WritePolicy
+		err     error
+		queued  []byte // 队列中剩下的消息 每条消息一个字节
+		aborted bool
+	}{
+		{name: "drop newest", policy: DropNewest, err: ErrQueueFull, queued: []byte{0, 1}},
+		{name: "drop oldest", policy: DropOldest, queued: []byte{1, 9}},
+		{name: "disconnect", policy: Disconnect, err: ErrSlowConsumer, queued: []byte{0, 1}, aborted: true},
+		{name: "default is disconnect", err: ErrSlowConsumer, queued: []byte{0, 1}, aborted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aborted := false
+			q := newSendQueue(WriteQueueConfig{Size: 2, Policy: tt.policy}, func() { aborted = true })
+			fillQueue(t, q)
+			if err := q.push([]byte{9}); !errors.Is(err, tt.err) {
+				t.Fatalf("push err = %v, want %v", err, tt.err)
+			}
+			if aborted != tt.aborted {
+				t.Fatalf("aborted = %t, want %t", aborted, tt.aborted)
+			}
+			if got := q.stats("c").Dropped; got != 1 {
+				t.Fatalf("dropped = %d, want 1", got)
+			}
+			var queued []byte
+			for len(q.ch) > 0 {
+				queued = append(queued, (<-q.ch)[0])
+			}
+			if string(queued) != string(tt.queued) {
+				t.Fatalf("queued = %v, want %v", queued, tt.queued)
+			}
+		})
+	}
+}
+
+func TestSendQueueTimeout(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+	tests := []struct {
+		name    string
+		push    func(q *sendQueue, buf []byte) error
+		drain   bool // 等待期间消费一条消息
+		err     error
+		minWait time.Duration
+		maxWait time.Duration
+	}{
+		{name: "push waits until consumed", push: (*sendQueue).push, drain: true, maxWait: timeout},
+		{name: "push waits for timeout", push: (*sendQueue).push, err: ErrQueueFull, minWait: timeout, maxWait: 2 * timeout},
+		{name: "tryPush never waits", push: (*sendQueue).tryPush, err: ErrQueueFull, maxWait: timeout / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSendQueue(WriteQueueConfig{Size: 1, Policy: DropNewest, SendTimeout: timeout}, nil)
+			fillQueue(t, q)
+			if tt.drain {
+				time.AfterFunc(timeout/4, func() { <-q.ch })
+			}
+			start := time.Now()
+			err := tt.push(q, []byte{9})
+			wait := time.Since(start)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if wait < tt.minWait || wait > tt.maxWait {
+				t.Fatalf("waited %v, want between %v and %v", wait, tt.minWait, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestSendQueueClosed(t *testing.T) {
+	q := newSendQueue(WriteQueueConfig{Size: 1}, nil)
+	close(q.closeChan)
+	if err := q.push([]byte{1}); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("push err = %v, want %v", err, ErrConnectionClosed)
+	}
+}
+
+func TestSendQueueStats(t *testing.T) {
+	q := newSendQueue(WriteQueueConfig{Size: 3, Policy: DropNewest}, nil)
+	fillQueue(t, q)
+	<-q.ch
+	stats := q.stats("c1")
+	want := ConnStats{Cid: "c1", QueueLen: 2, QueueCap: 3, MaxQueueLen: 3}
+	if stats != want {
+		t.Fatalf("stats = %+v, want %+v", stats, want)
+	}
+}
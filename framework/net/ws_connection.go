@@ -2,9 +2,11 @@ package net
 
 import (
 	"common/logs"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -22,37 +24,81 @@ import (
 var cidBase uint64 = 10000
 
 var (
-	pongWait             = 10 * time.Second
-	writeWait            = 10 * time.Second
-	pingInterval         = (pongWait * 9) / 10
-	maxMessageSize int64 = 1024
+	pongWait     = 10 * time.Second
+	writeWait    = 10 * time.Second
+	pingInterval = (pongWait * 9) / 10
 )
 
+var ErrConnectionClosed = errors.New("connection closed")
+
 type WsConnection struct {
 	Cid       string
 	Conn      *websocket.Conn
 	manager   *Manager
 	ReadChan  chan *MsgPack
 	WriteChan chan []byte
-	//Session   *Session
+	queue     *sendQueue
+	rl        *rateLimiter
+	closeChan chan struct{}
+	closeOnce sync.Once
+	Session   *Session
+	resumed   atomic.Bool // 用恢复凭证接管了原来的session 第一次握手时告诉客户端
 }
 
-//func (c *WsConnection) GetSession() *Session {
-//	return c.Session
-//}
-//
+func (c *WsConnection) GetSession() *Session {
+	return c.Session
+}
 
+// SendMessage 放入写队列 队列满时按配置的策略处理 返回的错误表示消息没有发出去
 func (c *WsConnection) SendMessage(buf []byte) error {
-	c.WriteChan <- buf
-	return nil
+	return c.queue.push(buf)
 }
 
-func (c *WsConnection) Close() {
+func (c *WsConnection) trySendMessage(buf []byte) error {
+	return c.queue.tryPush(buf)
+}
+
+func (c *WsConnection) sendHandshake(buf []byte) error {
+	return c.queue.release(buf)
+}
+
+func (c *WsConnection) takeResumed() bool {
+	return c.resumed.Swap(false)
+}
+
+func (c *WsConnection) Stats() ConnStats {
+	return c.queue.stats(c.Cid)
+}
+
+func (c *WsConnection) limiter() *rateLimiter {
+	return c.rl
+}
+
+// abort 慢连接不再等待队列写完 直接断开
+func (c *WsConnection) abort() {
+	logSlowConsumer(c.Cid, c.Stats())
+	c.Close()
 	if c.Conn != nil {
 		c.Conn.Close()
 	}
 }
 
+// Close 通知写协程把已经排队的消息写完再关闭连接 例如踢人的包
+func (c *WsConnection) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+	})
+}
+
+func (c *WsConnection) closed() bool {
+	select {
+	case <-c.closeChan:
+		return true
+	default:
+		return false
+	}
+}
+
 // Run 在此处进行读写消息
 func (c *WsConnection) Run() {
 	go c.readMessage()
@@ -64,8 +110,20 @@ func (c *WsConnection) Run() {
 func (c *WsConnection) writeMessage() {
 	//ping pong 间隔时，而且ping间隔时要比pong少
 	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		if c.Conn != nil {
+			c.Conn.Close()
+		}
+	}()
 	for {
 		select {
+		case <-c.closeChan:
+			c.flush()
+			if err := c.Conn.WriteControl(websocket.CloseMessage, nil, time.Now().Add(writeWait)); err != nil {
+				logs.Debug("client[%s] write close message err:%v", c.Cid, err)
+			}
+			return
 		case message, ok := <-c.WriteChan:
 			if !ok {
 				if err := c.Conn.WriteMessage(websocket.CloseMessage, nil); err != nil {
@@ -73,10 +131,15 @@ func (c *WsConnection) writeMessage() {
 				}
 				return
 			}
-			//正常收到消息
+			//正常收到消息 写超时需要每次重新设置
+			if err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+				logs.Error("client[%s] SetWriteDeadline err :%v", c.Cid, err)
+			}
 			if err := c.Conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
 				logs.Error("client[%s] write message err :%v", c.Cid, err)
+				return
 			}
+			c.queue.sent.Add(1)
 		case <-ticker.C:
 			if err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 				logs.Error("client[%s] ping SetWriteDeadline err :%v", c.Cid, err)
@@ -88,11 +151,32 @@ func (c *WsConnection) writeMessage() {
 	}
 }
 
+// flush 关闭前把队列中剩余的消息写出去
+func (c *WsConnection) flush() {
+	for {
+		select {
+		case message := <-c.WriteChan:
+			if err := c.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+				return
+			}
+			if err := c.Conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+				return
+			}
+			c.queue.sent.Add(1)
+		default:
+			return
+		}
+	}
+}
+
 func (c *WsConnection) readMessage() {
+	var readErr error
 	defer func() {
-		c.manager.removeClient(c)
+		//服务端主动关闭或者客户端正常关闭的不需要恢复
+		resumable := !c.closed() && !websocket.IsCloseError(readErr, websocket.CloseNormalClosure)
+		c.manager.removeClient(c, resumable)
 	}()
-	c.Conn.SetReadLimit(maxMessageSize)
+	c.Conn.SetReadLimit(c.manager.maxMessageSize())
 	if err := c.Conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
 		logs.Error("SetReadDeadline err:%v", err)
 		return
@@ -100,6 +184,7 @@ func (c *WsConnection) readMessage() {
 	for {
 		messageType, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			readErr = err
 			break
 		}
 		//客户端发来的消息是二进制消息
@@ -127,12 +212,21 @@ func (c *WsConnection) PongHandler(data string) error {
 
 func NewWsConnection(conn *websocket.Conn, manager *Manager) *WsConnection {
 	cid := fmt.Sprintf("%s-%s-%d", uuid.New().String(), manager.ServerId, atomic.AddUint64(&cidBase, 1))
-	return &WsConnection{
-		Conn:      conn,
-		manager:   manager,
-		Cid:       cid,
-		WriteChan: make(chan []byte, 1024),
-		ReadChan:  manager.ClientReadChan,
-		//Session:   NewSession(cid),
+	return newWsConnection(conn, manager, NewSession(cid, manager.ServerId))
+}
+
+// newWsConnection 恢复连接时沿用原来的session和cid
+func newWsConnection(conn *websocket.Conn, manager *Manager, session *Session) *WsConnection {
+	c := &WsConnection{
+		Conn:     conn,
+		manager:  manager,
+		Cid:      session.Cid(),
+		ReadChan: manager.ClientReadChan,
+		Session:  session,
+		rl:       newRateLimiter(manager.RateLimit, manager.maxMessageSize()),
 	}
+	c.queue = newSendQueue(manager.WriteQueue, c.abort)
+	c.WriteChan = c.queue.ch
+	c.closeChan = c.queue.closeChan
+	return c
 }
@@ -1,72 +1,277 @@
 package net
 
 import (
+	"common/biz"
 	"common/logs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"framework/game"
+	"framework/myError"
+	"framework/protocol"
+	"framework/remote"
+	"framework/serializer"
 	"github.com/gorilla/websocket"
+	"math/rand/v2"
+	gonet "net"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
-)
-
-var (
-	websocketUpgrade = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-	}
+	"sync/atomic"
+	"time"
 )
 
 type CheckOriginHandler func(r *http.Request) bool
 
+// EventHandler 按包类型处理客户端发来的数据包
+type EventHandler func(packet *protocol.Packet, c Connection) error
+
 type Manager struct {
 	sync.RWMutex
 	websocketUpgrade   *websocket.Upgrader
 	ServerId           string
-	CheckOriginHandler CheckOriginHandler
+	HeartTime          int           //客户端心跳间隔 秒 握手时下发
+	AuthRoute          string        //认证的路由 认证之前只能请求这个路由
+	AuthTimeout        time.Duration //连接后需要在这个时间内完成认证
+	WriteQueue         WriteQueueConfig
+	MaxMessageSize     int64 //客户端单条消息的最大字节数 超过后断开连接
+	RateLimit          game.RateLimitConfig
+	ResumeGrace        time.Duration //断线后保留session的时间 0表示不支持恢复
+	ResumeBuffer       int           //断线期间最多缓存的消息数
+	resumes            map[string]*suspendedConn
+	CheckOriginHandler CheckOriginHandler //为空时按AllowOrigins校验
+	AllowOrigins       []string
+	CertFile           string //证书和私钥都配置了使用wss
+	KeyFile            string
+	EnableCompression  bool     //permessage-deflate 需要客户端也支持
+	Serializers        []string //允许客户端协商的序列化方式 为空时允许所有注册的
 	clients            map[string]Connection
+	users              map[string]string // uid -> cid 用于按用户推送
 	ClientReadChan     chan *MsgPack
-	//handlers           map[protocol.PackageType]EventHandler
-	//ConnectorHandlers  LogicHandler
-	//RemoteReadChan     chan []byte
-	//RemoteCli          remote.Client
+	handlers           map[protocol.PackageType]EventHandler
+	ConnectorHandlers  LogicHandler
+	Channels           *ChannelService
+	Registry           UserRegistry
+	RemoteReadChan     chan []byte
+	RemoteCli          remote.Client
+	Transport          string //ws tcp both 默认ws
+	TcpAddr            string
+	server             *http.Server
+	tcpListener        gonet.Listener
+	draining           atomic.Bool
+	drainOnce          sync.Once
+	inflight           atomic.Int64  //正在处理的请求 包括转发给后端还没有回复的
+	RPCTimeout         time.Duration //转发给后端的请求等待回复的时间 默认5秒
+	pending            pendingRequests
+	HandleTimeout      time.Duration //本地handler的超时 0表示不限制
+	MaxRunRoutineNum   int           //同时执行的本地handler数量
+	workers            chan struct{}
 }
 
-func (m Manager) Run(addr string) {
+func (m *Manager) Run(addr string) {
+	maxRun := m.MaxRunRoutineNum
+	if maxRun <= 0 {
+		maxRun = defaultMaxRunRoutineNum
+	}
+	m.workers = make(chan struct{}, maxRun)
+	if m.Registry != nil {
+		go m.refreshRegistry()
+	}
 	go m.clientReadChanHandler()
-	http.HandleFunc("/", m.serveWS)
-	logs.Fatal("connector listen serve err:%v", http.ListenAndServe(addr, nil))
+	go m.remoteReadChanHandler()
+	m.setupEventHandlers()
+	switch m.Transport {
+	case TransportTcp:
+		m.serveTCP(m.TcpAddr)
+	case TransportBoth:
+		go m.serveTCP(m.TcpAddr)
+		m.serveWebsocket(addr)
+	default:
+		m.serveWebsocket(addr)
+	}
+}
+
+func (m *Manager) serveWebsocket(addr string) {
+	m.setupUpgrader()
+	//每个Manager使用自己的mux 同一个进程中可以运行多个connector
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", m.serveWS)
+	m.server = &http.Server{Addr: addr, Handler: mux}
+	var err error
+	if m.CertFile != "" && m.KeyFile != "" {
+		logs.Info("connector listen wss on %s", addr)
+		err = m.server.ListenAndServeTLS(m.CertFile, m.KeyFile)
+	} else {
+		err = m.server.ListenAndServe()
+	}
+	//Drain之后会关闭http服务 不需要退出进程
+	if !errors.Is(err, http.ErrServerClosed) {
+		logs.Fatal("connector listen serve err:%v", err)
+	}
+}
+
+// setupUpgrader websocket 基于http 升级时校验Origin 按配置开启压缩
+func (m *Manager) setupUpgrader() {
+	if m.CheckOriginHandler == nil {
+		m.CheckOriginHandler = OriginAllowlist(m.AllowOrigins)
+	}
+	m.websocketUpgrade = &websocket.Upgrader{
+		CheckOrigin:       m.CheckOriginHandler,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: m.EnableCompression,
+	}
 }
 
 func (m *Manager) serveWS(w http.ResponseWriter, r *http.Request) {
-	//websocket 基于http
-	if m.websocketUpgrade == nil {
-		m.websocketUpgrade = &websocketUpgrade
+	if m.draining.Load() {
+		http.Error(w, "connector is draining", http.StatusServiceUnavailable)
+		return
+	}
+	//带了恢复凭证的找回断线前的session
+	resume := r.URL.Query().Get("resume")
+	//升级时带了token的直接认证 token无效的不升级
+	var uid string
+	if token := r.URL.Query().Get("token"); token != "" {
+		var e *myError.Error
+		if uid, e = parseToken(token); e != nil {
+			http.Error(w, e.Error(), http.StatusUnauthorized)
+			return
+		}
 	}
 	wsConn, err := m.websocketUpgrade.Upgrade(w, r, nil)
 	if err != nil {
 		logs.Error("websocketUpgrade.Upgrade err:%v", err)
 		return
 	}
+	if resume != "" {
+		if client := m.resumeClient(wsConn, resume); client != nil {
+			client.Run()
+			return
+		}
+	}
 	//封装连接，方便加入一些我们需要的内容
 	client := NewWsConnection(wsConn, m)
 	m.addClient(client)
+	if uid != "" {
+		m.bindUser(client, uid)
+	} else {
+		m.checkAuthTimeout(client)
+	}
 	client.Run()
 }
 
-func (m *Manager) addClient(client *WsConnection) {
+func (m *Manager) addClient(client Connection) {
+	m.Lock()
+	defer m.Unlock()
+	m.clients[client.GetSession().Cid()] = client
+}
+
+// removeClient 连接的读协程退出时调用 resumable表示网络异常断开 可以在宽限期内恢复
+func (m *Manager) removeClient(conn Connection, resumable bool) {
+	if resumable && m.suspend(conn) {
+		conn.Close()
+		return
+	}
+	cid := conn.GetSession().Cid()
+	m.Lock()
+	c, ok := m.clients[cid]
+	ok = ok && c == conn
+	if ok {
+		delete(m.clients, cid)
+		if uid := c.GetSession().Uid(); uid != "" && m.users[uid] == cid {
+			delete(m.users, uid)
+		}
+	}
+	m.Unlock()
+	conn.Close()
+	if ok {
+		m.sessionClosed(c.GetSession())
+	}
+}
+
+// sessionClosed 连接断开 把用户移出channel 并通知session绑定的后端服务器
+func (m *Manager) sessionClosed(session *Session) {
+	uid := session.Uid()
+	if uid != "" {
+		m.Channels.MemberOffline(uid, session.Cid())
+		m.unregisterUser(uid, session.Cid())
+	}
+	if m.RemoteCli == nil {
+		return
+	}
+	for _, serverId := range session.Servers() {
+		msg := &remote.Msg{
+			Type:        remote.SessionClosedMsg,
+			Cid:         session.Cid(),
+			Uid:         uid,
+			Src:         m.ServerId,
+			Dst:         serverId,
+			SessionData: session.Data(),
+		}
+		data, err := msg.Encode()
+		if err != nil {
+			logs.Error("encode session closed msg err:%v", err)
+			return
+		}
+		if err := m.RemoteCli.SendMsg(serverId, data); err != nil {
+			logs.Error("send session closed msg to %s err:%v", serverId, err)
+		}
+	}
+}
+
+// syncUser session绑定了用户之后记录uid和cid的对应关系
+func (m *Manager) syncUser(c Connection) {
+	session := c.GetSession()
+	uid := session.Uid()
+	if uid == "" {
+		return
+	}
 	m.Lock()
 	defer m.Unlock()
-	m.clients[client.Cid] = client
+	if _, ok := m.clients[session.Cid()]; ok {
+		m.users[uid] = session.Cid()
+	}
+}
+
+func (m *Manager) getClientByUid(uid string) (Connection, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	cid, ok := m.users[uid]
+	if !ok {
+		return nil, false
+	}
+	c, ok := m.clients[cid]
+	return c, ok
+}
+
+// ClientCount 当前的连接数 包括等待恢复的
+func (m *Manager) ClientCount() int {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.clients)
 }
 
-func (m *Manager) removeClient(wc *WsConnection) {
-	for cid, c := range m.clients {
-		if cid == wc.Cid {
-			c.Close()
-			delete(m.clients, cid)
+// ConnStats 所有连接写队列的统计
+func (m *Manager) ConnStats() []ConnStats {
+	m.RLock()
+	defer m.RUnlock()
+	stats := make([]ConnStats, 0, len(m.clients))
+	for _, c := range m.clients {
+		if sc, ok := c.(interface{ Stats() ConnStats }); ok {
+			stats = append(stats, sc.Stats())
 		}
 	}
+	return stats
+}
+
+func (m *Manager) getClient(cid string) (Connection, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	c, ok := m.clients[cid]
+	return c, ok
 }
 
 func (m *Manager) clientReadChanHandler() {
@@ -82,30 +287,427 @@ func (m *Manager) clientReadChanHandler() {
 
 // 解析协议
 func (m *Manager) decodeClientPack(body *MsgPack) {
-	//解析协议
-	logs.Info("receiver message:%v", string(body.Body))
-	//packet, err := protocol.Decode(body.Body)
-	//if err != nil {
-	//	logs.Error("decode message err:%v", err)
-	//	return
-	//}
-	//if err := m.routeEvent(packet, body.Cid); err != nil {
-	//	logs.Error("routeEvent err:%v", err)
-	//}
-}
-
-func (m Manager) Close() {
-	for cid, v := range m.clients {
+	packet, err := protocol.Decode(body.Body)
+	if err != nil {
+		logs.Error("decode message err:%v", err)
+		return
+	}
+	if err := m.routeEvent(packet, body.Cid); err != nil {
+		logs.Error("routeEvent err:%v", err)
+	}
+}
+
+// routeEvent 根据包类型分发给对应的处理器
+func (m *Manager) routeEvent(packet *protocol.Packet, cid string) error {
+	c, ok := m.getClient(cid)
+	if !ok {
+		return errors.New("no client found")
+	}
+	handler, ok := m.handlers[packet.Type]
+	if !ok {
+		return errors.New("no packetType found")
+	}
+	return handler(packet, c)
+}
+
+func (m *Manager) setupEventHandlers() {
+	m.handlers[protocol.Handshake] = m.HandshakeHandler
+	m.handlers[protocol.HandshakeAck] = m.HandshakeAckHandler
+	m.handlers[protocol.Heartbeat] = m.HeartbeatHandler
+	m.handlers[protocol.Data] = m.MessageHandler
+	m.handlers[protocol.Kick] = m.KickHandler
+}
+
+// heartbeat 握手时下发给客户端的心跳间隔 秒
+func (m *Manager) heartbeat() int {
+	if m.HeartTime <= 0 {
+		return protocol.DefaultHeartbeatSeconds
+	}
+	return m.HeartTime
+}
+
+// tcpReadTimeout tcp客户端靠心跳保活 允许丢一次心跳
+func (m *Manager) tcpReadTimeout() time.Duration {
+	return 2 * time.Duration(m.heartbeat()) * time.Second
+}
+
+// HandshakeHandler 握手 校验客户端版本 回复心跳间隔 路由字典和服务器时间
+func (m *Manager) HandshakeHandler(packet *protocol.Packet, c Connection) error {
+	var body protocol.HandshakeBody
+	if err := json.Unmarshal(packet.Body, &body); err != nil {
+		m.Kick(c, protocol.KickBody{
+			Code:   protocol.HandshakeBadRequest,
+			Reason: "invalid handshake",
+		})
+		return err
+	}
+	minVersion := game.Conf.GetString("minClientVersion")
+	if minVersion != "" && compareVersion(body.Sys.Version, minVersion) < 0 {
+		logs.Warn("client version too low, version=%s, min=%s", body.Sys.Version, minVersion)
+		m.Kick(c, protocol.KickBody{
+			Code:        protocol.HandshakeVersionTooLow,
+			Reason:      "client version too low",
+			DownloadUrl: game.Conf.GetString("downloadUrl"),
+		})
+		return nil
+	}
+	session := c.GetSession()
+	session.Put("version", body.Sys.Version)
+	session.Put("platform", body.Sys.Platform)
+	session.SetSerializer(m.negotiateSerializer(body.Sys.Serializer))
+	resumeToken, resumed := m.issueResumeToken(c)
+	res := protocol.HandshakeResponse{
+		Code: protocol.HandshakeOK,
+		Sys: protocol.HandshakeSys{
+			Heartbeat:   m.heartbeat(),
+			Dict:        protocol.GetDictionary(),
+			Serializer:  session.Serializer().Name(),
+			ServerTime:  time.Now().UnixMilli(),
+			ResumeToken: resumeToken,
+			Resumed:     resumed,
+		},
+	}
+	data, _ := json.Marshal(res)
+	buf, err := protocol.Encode(packet.Type, data)
+	if err != nil {
+		return err
+	}
+	if h, ok := c.(handshaker); ok {
+		return h.sendHandshake(buf)
+	}
+	return c.SendMessage(buf)
+}
+
+// negotiateSerializer 客户端期望的序列化方式没有注册或者没有开放时使用json
+// 客户端以握手回复中的serializer为准
+func (m *Manager) negotiateSerializer(name string) string {
+	if name == "" {
+		return protocol.DefaultSerializer
+	}
+	if _, ok := serializer.Get(name); !ok {
+		logs.Warn("client serializer %s not registered, use %s", name, protocol.DefaultSerializer)
+		return protocol.DefaultSerializer
+	}
+	if len(m.Serializers) > 0 && !slices.Contains(m.Serializers, name) {
+		logs.Warn("client serializer %s not allowed, use %s", name, protocol.DefaultSerializer)
+		return protocol.DefaultSerializer
+	}
+	return name
+}
+
+func (m *Manager) HandshakeAckHandler(packet *protocol.Packet, c Connection) error {
+	logs.Info("receiver handshake ack message...")
+	return nil
+}
+
+func (m *Manager) HeartbeatHandler(packet *protocol.Packet, c Connection) error {
+	buf, err := protocol.Encode(packet.Type, []byte{})
+	if err != nil {
+		return err
+	}
+	return c.SendMessage(buf)
+}
+
+// MessageHandler 处理数据包 路由属于connector的本地处理 其他的转发给对应类型的服务器
+func (m *Manager) MessageHandler(packet *protocol.Packet, c Connection) error {
+	message, err := protocol.MessageDecode(packet.Body)
+	if err != nil {
+		return err
+	}
+	if message.Type != protocol.Request && message.Type != protocol.Notify {
+		return fmt.Errorf("unsupported message type from client: %v", message.Type)
+	}
+	if !m.checkRate(c, message, len(packet.Body)) {
+		return nil
+	}
+	m.inflight.Add(1)
+	defer m.inflight.Add(-1)
+	//没有认证的连接只能发送认证请求 其他的直接踢下线
+	if message.Route == m.authRoute() {
+		m.authHandler(c, message)
+		return nil
+	}
+	if c.GetSession().Uid() == "" {
+		m.kickUnauthorized(c)
+		return fmt.Errorf("unauthorized client send route=%s", message.Route)
+	}
+	route, err := protocol.ParseRoute(message.Route)
+	if err != nil {
+		m.response(c, message, nil, biz.RouteNotFound)
+		return err
+	}
+	connectorConfig := game.Conf.GetConnector(m.ServerId)
+	if connectorConfig != nil && route.ServerType == connectorConfig.ServerType {
+		handler, ok := m.ConnectorHandlers[message.Route]
+		if !ok {
+			m.response(c, message, nil, biz.RouteNotFound)
+			return fmt.Errorf("no handler found, route=%s", message.Route)
+		}
+		m.runLocal(c, message, handler)
+		return nil
+	}
+	return m.forward(c, route, message)
+}
+
+// runLocal 本地handler在单独的协程中执行 例如访问数据库 不阻塞其他连接的消息
+// 同时执行的数量达到MaxRunRoutineNum时直接回复服务器繁忙 超过HandleTimeout回复超时并释放名额
+func (m *Manager) runLocal(c Connection, message *protocol.Message, handler HandlerFunc) {
+	select {
+	case m.workers <- struct{}{}:
+	default:
+		logs.Warn("connector workers are busy, route=%s, uid=%s", message.Route, c.GetSession().Uid())
+		m.response(c, message, nil, biz.ServerBusy)
+		return
+	}
+	m.inflight.Add(1)
+	go func() {
+		defer func() {
+			m.inflight.Add(-1)
+			<-m.workers
+		}()
+		res, e, running := CallWithTimeout(handler, c.GetSession(), message.Data, m.HandleTimeout)
+		if running != nil {
+			logs.Warn("connector handle timeout, route=%s, uid=%s, timeout=%v", message.Route, c.GetSession().Uid(), m.HandleTimeout)
+			m.response(c, message, nil, biz.RequestTimeout)
+			return
+		}
+		m.syncUser(c)
+		m.response(c, message, res, e)
+	}()
+}
+
+func (m *Manager) maxMessageSize() int64 {
+	if m.MaxMessageSize <= 0 {
+		return defaultMaxMessageSize
+	}
+	return m.MaxMessageSize
+}
+
+func (m *Manager) authRoute() string {
+	if m.AuthRoute == "" {
+		return DefaultAuthRoute
+	}
+	return m.AuthRoute
+}
+
+// forward 转发给后端服务器 优先使用session已经绑定的服务器
+func (m *Manager) forward(c Connection, route *protocol.Route, message *protocol.Message) error {
+	session := c.GetSession()
+	serverId, e := m.selectServer(session, route.ServerType)
+	if e != nil {
+		m.response(c, message, nil, e)
+		return e
+	}
+	if m.RemoteCli == nil {
+		m.response(c, message, nil, biz.ServerUnavailable)
+		return errors.New("remote client not running")
+	}
+	msg := &remote.Msg{
+		Type:        remote.RequestMsg,
+		Cid:         session.Cid(),
+		Uid:         session.Uid(),
+		Src:         m.ServerId,
+		Dst:         serverId,
+		Body:        message,
+		SessionData: session.Data(),
+		Servers:     session.Servers(),
+		Serializer:  session.Serializer().Name(),
+	}
+	data, err := msg.Encode()
+	if err != nil {
+		m.response(c, message, nil, biz.Fail)
+		return err
+	}
+	var key pendingKey
+	if message.Type == protocol.Request {
+		//收到后端的回复或者超时才算处理完
+		key = m.addPending(session.Cid(), message)
+	}
+	if err := m.RemoteCli.SendMsg(serverId, data); err != nil {
+		if message.Type == protocol.Request {
+			m.donePending(key)
+		}
+		//发送失败解除绑定 下次重新选择服务器
+		session.UnbindServer(route.ServerType)
+		m.response(c, message, nil, biz.ServerUnavailable)
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) remoteReadChanHandler() {
+	for {
+		select {
+		case data, ok := <-m.RemoteReadChan:
+			if ok {
+				m.decodeRemoteMsg(data)
+			}
+		}
+	}
+}
+
+// decodeRemoteMsg 处理后端服务器发来的消息 同步session 把响应回复给客户端
+func (m *Manager) decodeRemoteMsg(data []byte) {
+	msg, err := remote.DecodeMsg(data)
+	if err != nil {
+		logs.Error("decode remote msg err:%v", err)
+		return
+	}
+	switch msg.Type {
+	case remote.PushMsg:
+		m.remotePush(msg)
+		return
+	case remote.KickMsg:
+		m.remoteKick(msg)
+		return
+	case remote.PushAckMsg:
+		if len(msg.Failed) > 0 {
+			logs.Debug("push to %s failed targets:%v", msg.Src, msg.Failed)
+		}
+		return
+	}
+	//已经超时回复过客户端的 只同步session
+	reply := msg.Type == remote.ResponseMsg && msg.Body != nil
+	if reply && !m.donePending(pendingKey{cid: msg.Cid, id: msg.Body.ID}) {
+		logs.Warn("remote response after timeout, cid=%s, src=%s", msg.Cid, msg.Src)
+		reply = false
+	}
+	c, ok := m.getClient(msg.Cid)
+	if !ok {
+		logs.Warn("remote msg client not found, cid=%s", msg.Cid)
+		return
+	}
+	//用户只能通过connector的认证绑定 后端修改的uid不生效 否则会绕过重复登录检测和在线注册
+	if ch := msg.Changes; ch != nil && ch.Uid != "" && ch.Uid != c.GetSession().Uid() {
+		logs.Warn("client[%s] ignore uid %s bound by %s", msg.Cid, ch.Uid, msg.Src)
+		ch.Uid = ""
+	}
+	//只合并变化的部分 同时绑定了hall和game的session不会被其中一台的回复覆盖
+	c.GetSession().ApplyChanges(msg.Changes)
+	m.syncUser(c)
+	if !reply {
+		return
+	}
+	buf, err := encodeData(msg.Body)
+	if err != nil {
+		logs.Error("encode remote response err:%v", err)
+		return
+	}
+	if err := c.SendMessage(buf); err != nil {
+		logs.Error("send remote response err:%v", err)
+	}
+}
+
+// selectServer 选择转发的目标服务器 选中后绑定到session上 后续消息都发往同一台
+// 绑定的服务器已经从配置中移除时重新选择
+func (m *Manager) selectServer(session *Session, serverType string) (string, *myError.Error) {
+	if serverId, ok := session.GetServer(serverType); ok {
+		if game.Conf.GetServer(serverId) != nil {
+			return serverId, nil
+		}
+		logs.Warn("session bound server %s not found, select again", serverId)
+	}
+	servers := game.Conf.ServersConf().TypeServer[serverType]
+	if len(servers) == 0 {
+		return "", biz.RouteNotFound
+	}
+	serverId := servers[rand.IntN(len(servers))].ID
+	session.BindServer(serverType, serverId)
+	return serverId, nil
+}
+
+// response 回复客户端的请求 通知类消息不需要回复
+func (m *Manager) response(c Connection, req *protocol.Message, res any, e *myError.Error) {
+	if req.Type != protocol.Request {
+		if e != nil {
+			logs.Error("handle notify err, route=%s, err:%v", req.Route, e)
+		}
+		return
+	}
+	data, isErr := EncodeResult(c.GetSession().Serializer(), res, e)
+	buf, err := encodeData(&protocol.Message{
+		Type:  protocol.Response,
+		ID:    req.ID,
+		Data:  data,
+		Error: isErr,
+	})
+	if err != nil {
+		logs.Error("encode response err:%v", err)
+		return
+	}
+	if err := c.SendMessage(buf); err != nil {
+		logs.Error("send response err:%v", err)
+	}
+}
+
+// KickHandler 踢人的包只能由服务端发出
+func (m *Manager) KickHandler(packet *protocol.Packet, c Connection) error {
+	logs.Warn("client should not send kick packet")
+	return nil
+}
+
+// Kick 发送踢人包后关闭连接
+func (m *Manager) Kick(c Connection, body protocol.KickBody) {
+	data, _ := json.Marshal(body)
+	buf, err := protocol.Encode(protocol.Kick, data)
+	if err != nil {
+		logs.Error("encode kick packet err:%v", err)
+	} else if err := c.SendMessage(buf); err != nil {
+		logs.Error("send kick packet err:%v", err)
+	}
+	c.Close()
+}
+
+// compareVersion 比较形如1.2.3的版本号 a<b返回-1 相等返回0 a>b返回1
+func compareVersion(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Close 关闭所有连接 连接的读协程退出后会走removeClient清理用户和session
+func (m *Manager) Close() {
+	for _, v := range m.allClients() {
 		v.Close()
-		delete(m.clients, cid)
 	}
 }
 
+// allClients 所有连接的快照 遍历时不持有锁
+func (m *Manager) allClients() []Connection {
+	m.RLock()
+	defer m.RUnlock()
+	clients := make([]Connection, 0, len(m.clients))
+	for _, v := range m.clients {
+		clients = append(clients, v)
+	}
+	return clients
+}
+
 func NewManager() *Manager {
-	return &Manager{
+	m := &Manager{
 		ClientReadChan: make(chan *MsgPack, 1024),
 		clients:        make(map[string]Connection),
-		//handlers:       make(map[protocol.PackageType]EventHandler),
-		//RemoteReadChan: make(chan []byte, 1024),
+		users:          make(map[string]string),
+		handlers:       make(map[protocol.PackageType]EventHandler),
+		RemoteReadChan: make(chan []byte, 1024),
+		resumes:        make(map[string]*suspendedConn),
+		pending:        pendingRequests{timers: make(map[pendingKey]*time.Timer)},
 	}
+	m.Channels = NewChannelService(m)
+	return m
 }
@@ -0,0 +1,109 @@
+package net
+
+import (
+	"common/biz"
+	"encoding/json"
+	"framework/myError"
+	"framework/protocol"
+	"testing"
+	"time"
+)
+
+// waitMessages 等待连接收到n条消息 按顺序解码成回复
+func waitMessages(t *testing.T, c *fakeConn, n int) []*protocol.Message {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(c.messages()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d messages, want %d", len(c.messages()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var msgs []*protocol.Message
+	for _, buf := range c.messages() {
+		packet, err := protocol.Decode(buf)
+		if err != nil {
+			t.Fatalf("decode packet err = %v", err)
+		}
+		msg, err := protocol.MessageDecode(packet.Body)
+		if err != nil {
+			t.Fatalf("decode message err = %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestRunLocal(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		busy    bool // 名额已经被占满
+		handler HandlerFunc
+		code    int // 0表示正常回复
+	}{
+		{
+			name:    "reply result",
+			timeout: time.Second,
+			handler: func(*Session, []byte) (any, *myError.Error) { return map[string]string{"a": "b"}, nil },
+		},
+		{
+			name:    "reply error",
+			handler: func(*Session, []byte) (any, *myError.Error) { return nil, biz.RequestDataError },
+			code:    biz.RequestDataError.Code,
+		},
+		{
+			name:    "workers busy",
+			busy:    true,
+			handler: func(*Session, []byte) (any, *myError.Error) { return nil, nil },
+			code:    biz.ServerBusy.Code,
+		},
+		{
+			name:    "handle timeout",
+			timeout: 20 * time.Millisecond,
+			handler: func(*Session, []byte) (any, *myError.Error) {
+				<-block
+				return nil, nil
+			},
+			code: biz.RequestTimeout.Code,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager()
+			m.HandleTimeout = tt.timeout
+			m.workers = make(chan struct{}, 1)
+			if tt.busy {
+				m.workers <- struct{}{}
+			}
+			c := newFakeConn("c1")
+			m.runLocal(c, &protocol.Message{Type: protocol.Request, ID: 7, Route: "connector.h.m"}, tt.handler)
+			msg := waitMessages(t, c, 1)[0]
+			if msg.ID != 7 || msg.Error != (tt.code != 0) {
+				t.Fatalf("response id = %d error = %t, want id 7 error %t", msg.ID, msg.Error, tt.code != 0)
+			}
+			if tt.code != 0 {
+				body := &ErrorBody{}
+				if err := json.Unmarshal(msg.Data, body); err != nil {
+					t.Fatalf("decode error body err = %v", err)
+				}
+				if int(body.Code) != tt.code {
+					t.Fatalf("code = %d, want %d", body.Code, tt.code)
+				}
+			}
+			if tt.busy {
+				return
+			}
+			//回复之后释放名额 超时的handler还在执行也不占用
+			deadline := time.Now().Add(time.Second)
+			for len(m.workers) != 0 || m.inflight.Load() != 0 {
+				if time.Now().After(deadline) {
+					t.Fatalf("workers = %d, inflight = %d after response", len(m.workers), m.inflight.Load())
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package node
+
+import (
+	"common/biz"
+	"common/logs"
+	"framework/game"
+	"framework/myError"
+	"framework/net"
+	"framework/protocol"
+	"framework/remote"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRunRoutineNum = 1024
+	defaultRPCTimeout       = 5 * time.Second
+)
+
+// App 后端服务器(hall game等) 接收connector转发过来的消息 交给对应的handler处理
+type App struct {
+	sync.RWMutex
+	serverId      string
+	remoteCli     remote.Client
+	readChan      chan []byte
+	msgChan       chan *remote.Msg
+	handlers      net.LogicHandler
+	users         map[string]string // uid -> connectorId
+	cids          map[string]string // cid -> connectorId
+	pending       map[uint64]chan []string
+	seq           uint64
+	Channels      *net.ChannelService
+	workers       chan struct{} //限制同时执行的handler数量 MaxRunRoutineNum
+	handleTimeout time.Duration //0表示不限制
+	rpcTimeout    time.Duration
+	orderKey      OrderKeyFunc
+	ordered       orderQueues
+}
+
+func Default() *App {
+	a := &App{
+		readChan: make(chan []byte, 1024),
+		msgChan:  make(chan *remote.Msg, 1024),
+		handlers: make(net.LogicHandler),
+		users:    make(map[string]string),
+		cids:     make(map[string]string),
+		pending:  make(map[uint64]chan []string),
+		orderKey: OrderBySession,
+		ordered:  orderQueues{queues: make(map[string]*orderQueue), cidKeys: make(map[string]string)},
+	}
+	a.Channels = net.NewChannelService(a)
+	return a
+}
+
+func (a *App) Run(serverId string) error {
+	a.serverId = serverId
+	a.setup()
+	a.remoteCli = remote.NewClient(serverId, a.readChan)
+	if err := a.remoteCli.Run(); err != nil {
+		return err
+	}
+	go a.readChanMsg()
+	go a.handleMsg()
+	return nil
+}
+
+// setup 按servers.json中的配置设置并发数和超时
+func (a *App) setup() {
+	maxRun := defaultMaxRunRoutineNum
+	a.rpcTimeout = defaultRPCTimeout
+	var conf *game.ServersConfig
+	if game.Conf != nil {
+		conf = game.Conf.GetServer(a.serverId)
+	}
+	if conf != nil {
+		if conf.MaxRunRoutineNum > 0 {
+			maxRun = conf.MaxRunRoutineNum
+		}
+		if conf.RPCTimeOut > 0 {
+			a.rpcTimeout = time.Duration(conf.RPCTimeOut) * time.Second
+		}
+		a.handleTimeout = time.Duration(conf.HandleTimeOut) * time.Second
+	}
+	a.workers = make(chan struct{}, maxRun)
+}
+
+func (a *App) Close() {
+	if a.remoteCli != nil {
+		if err := a.remoteCli.Close(); err != nil {
+			logs.Error("node close remote client err:%v", err)
+		}
+	}
+}
+
+func (a *App) RegisterHandler(handlers net.LogicHandler) {
+	a.handlers = handlers
+}
+
+func (a *App) readChanMsg() {
+	for {
+		select {
+		case data, ok := <-a.readChan:
+			if ok {
+				msg, err := remote.DecodeMsg(data)
+				if err != nil {
+					logs.Error("node decode remote msg err:%v", err)
+					continue
+				}
+				if msg.Type == remote.PushAckMsg {
+					a.pushAck(msg)
+					continue
+				}
+				if msg.Type == remote.RequestMsg {
+					a.track(msg)
+				}
+				a.msgChan <- msg
+			}
+		}
+	}
+}
+
+// handleMsg handler在单独的协程中执行 handler里推送消息时等待的ack不会被阻塞
+// 同一个顺序key的消息依次执行 同时执行的handler达到MaxRunRoutineNum时 后面的消息排队等待
+func (a *App) handleMsg() {
+	for msg := range a.msgChan {
+		a.schedule(msg)
+	}
+}
+
+// newSession 用消息带过来的数据还原session
+func (a *App) newSession(msg *remote.Msg) *net.Session {
+	session := net.NewSession(msg.Cid, msg.Src)
+	session.SetData(msg.Uid, msg.SessionData, msg.Servers)
+	session.SetSerializer(msg.Serializer)
+	return session
+}
+
+// dispatch 调用handler 把结果和session修改过的部分一起回复给connector 返回同步给connector的修改
+func (a *App) dispatch(msg *remote.Msg, session *net.Session) *remote.SessionChanges {
+	if msg.Type == remote.SessionClosedMsg {
+		a.sessionClosed(msg)
+		return nil
+	}
+	if msg.Type != remote.RequestMsg || msg.Body == nil {
+		logs.Warn("node unsupported remote msg, type=%d", msg.Type)
+		return nil
+	}
+	res, e, running := a.handle(session, msg)
+	if running != nil {
+		//超时的请求只回复错误 handler还在修改的session不同步
+		a.reply(msg, session, &remote.Msg{
+			Type: remote.SessionSyncMsg,
+			Cid:  msg.Cid,
+			Src:  a.serverId,
+			Dst:  msg.Src,
+		}, nil, biz.RequestTimeout)
+		//等handler结束再释放worker 保证同时执行的handler不超过MaxRunRoutineNum
+		<-running
+		logs.Warn("node handler finished after timeout, route=%s", msg.Body.Route)
+		return nil
+	}
+	changes := session.Changes()
+	a.reply(msg, session, &remote.Msg{
+		Type:    remote.SessionSyncMsg,
+		Cid:     msg.Cid,
+		Uid:     session.Uid(),
+		Src:     a.serverId,
+		Dst:     msg.Src,
+		Changes: changes,
+	}, res, e)
+	return changes
+}
+
+// handle 在HandleTimeOut内执行handler 超时返回的running在handler真正结束时关闭
+func (a *App) handle(session *net.Session, msg *remote.Msg) (any, *myError.Error, <-chan struct{}) {
+	handler, found := a.handlers[msg.Body.Route]
+	if !found {
+		return nil, biz.RouteNotFound, nil
+	}
+	res, e, running := net.CallWithTimeout(handler, session, msg.Body.Data, a.handleTimeout)
+	if running != nil {
+		logs.Warn("node handle timeout, route=%s, uid=%s, timeout=%v", msg.Body.Route, msg.Uid, a.handleTimeout)
+	}
+	return res, e, running
+}
+
+// reply 把结果和session的变化一起回复给connector 通知类消息只同步session
+func (a *App) reply(msg *remote.Msg, session *net.Session, reply *remote.Msg, res any, e *myError.Error) {
+	if msg.Body.Type == protocol.Request {
+		data, isErr := net.EncodeResult(session.Serializer(), res, e)
+		reply.Type = remote.ResponseMsg
+		reply.Body = &protocol.Message{
+			Type:  protocol.Response,
+			ID:    msg.Body.ID,
+			Data:  data,
+			Error: isErr,
+		}
+	} else if e != nil {
+		logs.Error("node handle notify err, route=%s, err:%v", msg.Body.Route, e)
+	}
+	data, err := reply.Encode()
+	if err != nil {
+		logs.Error("node encode reply err:%v", err)
+		return
+	}
+	if err := a.remoteCli.SendMsg(msg.Src, data); err != nil {
+		logs.Error("node send reply to %s err:%v", msg.Src, err)
+	}
+}
+
+// sessionClosed 用户断开连接 移出所有channel 最后一个成员离开的channel会被销毁
+func (a *App) sessionClosed(msg *remote.Msg) {
+	if msg.Uid != "" {
+		a.Channels.MemberOffline(msg.Uid, msg.Cid)
+	}
+	a.Lock()
+	defer a.Unlock()
+	delete(a.cids, msg.Cid)
+	if a.users[msg.Uid] == msg.Src {
+		delete(a.users, msg.Uid)
+	}
+}
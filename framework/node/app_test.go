@@ -0,0 +1,89 @@
+package node
+
+import (
+	"common/biz"
+	"encoding/json"
+	"framework/myError"
+	"framework/net"
+	"framework/protocol"
+	"framework/remote"
+	"testing"
+	"time"
+)
+
+func TestDispatch(t *testing.T) {
+	release := make(chan struct{})
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		route   string
+		code    int  // 0表示正常回复
+		changes bool // 回复中带session的修改
+	}{
+		{name: "reply with changes", timeout: time.Second, route: "bind", changes: true},
+		{name: "route not found", route: "unknown", code: biz.RouteNotFound.Code},
+		{name: "handler error", route: "fail", code: biz.Fail.Code},
+		{name: "timeout without changes", timeout: 20 * time.Millisecond, route: "block", code: biz.RequestTimeout.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, cli := newTestApp(1)
+			a.handleTimeout = tt.timeout
+			a.RegisterHandler(net.LogicHandler{
+				"bind": func(s *net.Session, _ []byte) (any, *myError.Error) {
+					s.Bind("u1")
+					return map[string]string{"a": "b"}, nil
+				},
+				"fail": func(*net.Session, []byte) (any, *myError.Error) { return nil, biz.Fail },
+				"block": func(s *net.Session, _ []byte) (any, *myError.Error) {
+					<-release
+					s.Bind("u1")
+					return nil, nil
+				},
+			})
+			go a.handleMsg()
+			defer close(a.msgChan)
+			a.msgChan <- &remote.Msg{
+				Type: remote.RequestMsg,
+				Cid:  "c1",
+				Src:  "connector-test",
+				Body: &protocol.Message{Type: protocol.Request, ID: 3, Route: tt.route},
+			}
+			reply := cli.wait(t, 1)[0]
+			if reply.Type != remote.ResponseMsg || reply.Dst != "connector-test" || reply.Body.ID != 3 {
+				t.Fatalf("reply = %+v, want response 3 to connector-test", reply)
+			}
+			if reply.Body.Error != (tt.code != 0) {
+				t.Fatalf("reply error = %t, want %t", reply.Body.Error, tt.code != 0)
+			}
+			if tt.code != 0 {
+				body := &net.ErrorBody{}
+				if err := json.Unmarshal(reply.Body.Data, body); err != nil {
+					t.Fatalf("decode error body err = %v", err)
+				}
+				if int(body.Code) != tt.code {
+					t.Fatalf("code = %d, want %d", body.Code, tt.code)
+				}
+			}
+			if (reply.Changes != nil && reply.Changes.Uid == "u1") != tt.changes {
+				t.Fatalf("changes = %+v, want changes %t", reply.Changes, tt.changes)
+			}
+			if tt.route != "block" {
+				return
+			}
+			//超时的handler结束之前一直占用worker
+			time.Sleep(20 * time.Millisecond)
+			if len(a.workers) != 1 {
+				t.Fatalf("workers = %d while timed out handler running, want 1", len(a.workers))
+			}
+			close(release)
+			deadline := time.Now().Add(time.Second)
+			for len(a.workers) != 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("worker not released after handler finished")
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"common/config"
+	"common/logs"
+	"framework/remote"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	config.Conf = &config.Config{}
+	logs.InitLog("node_test")
+	os.Exit(m.Run())
+}
+
+// fakeClient 记录回复给connector的消息 不经过nats
+type fakeClient struct {
+	sync.Mutex
+	sent []*remote.Msg
+}
+
+func (c *fakeClient) Run() error   { return nil }
+func (c *fakeClient) Close() error { return nil }
+
+func (c *fakeClient) SendMsg(dst string, data []byte) error {
+	msg, err := remote.DecodeMsg(data)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+// wait 等待收到n条回复
+func (c *fakeClient) wait(t *testing.T, n int) []*remote.Msg {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.Lock()
+		sent := append([]*remote.Msg(nil), c.sent...)
+		c.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d replies, want %d", len(sent), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestApp(workers int) (*App, *fakeClient) {
+	cli := &fakeClient{}
+	a := Default()
+	a.serverId = "game-test"
+	a.remoteCli = cli
+	a.workers = make(chan struct{}, workers)
+	return a, cli
+}
@@ -0,0 +1,149 @@
+package node
+
+import (
+	"fmt"
+	"framework/net"
+	"framework/remote"
+	"slices"
+	"sync"
+)
+
+// OrderKeyFunc 返回消息的顺序key 相同key的消息按收到的顺序依次执行 不同key的并行执行
+// route为空表示连接断开的通知 返回空字符串表示不需要保证顺序
+type OrderKeyFunc func(session *net.Session, route string) string
+
+// OrderBySession 默认按连接保证顺序 同一个玩家的出牌和过牌不会乱序
+func OrderBySession(session *net.Session, route string) string {
+	return session.Cid()
+}
+
+// OrderBySessionKey 按session中保存的数据保证顺序 例如房间id 同一个房间的消息依次执行
+// session中没有这个数据时按连接保证顺序
+func OrderBySessionKey(key string) OrderKeyFunc {
+	return func(session *net.Session, route string) string {
+		if v, ok := session.Get(key); ok && v != nil {
+			return fmt.Sprintf("%s:%v", key, v)
+		}
+		return OrderBySession(session, route)
+	}
+}
+
+// orderQueue 一个key上等待执行的消息 同一时间只有一个协程在执行
+// 排队的消息带的是connector发送时的session 前面的消息对session的修改记在changes中
+// 执行时合并进去 后面的handler才能看到 例如join中保存的roomId在ready中可以读到
+type orderQueue struct {
+	msgs    []*remote.Msg
+	changes map[string]*remote.SessionChanges // cid -> 已经执行的消息对session的修改
+	cids    map[string]bool                   // 排过队的连接 队列结束时清理cidKeys
+}
+
+// orderQueues cidKeys记录每个连接的消息当前在哪个key上排队
+// 例如join之后key从cid变成房间 之前的消息还没执行完时 后面的消息继续排在原来的key上 等它执行完再切换
+type orderQueues struct {
+	sync.Mutex
+	queues  map[string]*orderQueue
+	cidKeys map[string]string
+}
+
+// SetOrderKey 设置顺序key 需要在Run之前调用
+func (a *App) SetOrderKey(fn OrderKeyFunc) {
+	a.orderKey = fn
+}
+
+// schedule key上已经有消息在执行时排到后面 否则占用一个worker开始执行
+func (a *App) schedule(msg *remote.Msg) {
+	key := a.orderKey(a.newSession(msg), route(msg))
+	if key == "" {
+		a.workers <- struct{}{}
+		go func() {
+			defer func() { <-a.workers }()
+			a.dispatch(msg, a.newSession(msg))
+		}()
+		return
+	}
+	a.ordered.Lock()
+	if old, ok := a.ordered.cidKeys[msg.Cid]; ok {
+		key = old
+	}
+	a.ordered.cidKeys[msg.Cid] = key
+	if q, ok := a.ordered.queues[key]; ok {
+		q.msgs = append(q.msgs, msg)
+		q.cids[msg.Cid] = true
+		a.ordered.Unlock()
+		return
+	}
+	q := &orderQueue{
+		msgs:    []*remote.Msg{msg},
+		changes: make(map[string]*remote.SessionChanges),
+		cids:    map[string]bool{msg.Cid: true},
+	}
+	a.ordered.queues[key] = q
+	a.ordered.Unlock()
+	a.workers <- struct{}{}
+	go a.runQueue(key, q)
+}
+
+// runQueue 依次执行key上的消息 执行完后删除这个key
+// session在出队时才还原 合并前面的消息已经做的修改
+func (a *App) runQueue(key string, q *orderQueue) {
+	defer func() { <-a.workers }()
+	for {
+		a.ordered.Lock()
+		if len(q.msgs) == 0 {
+			delete(a.ordered.queues, key)
+			for cid := range q.cids {
+				if a.ordered.cidKeys[cid] == key {
+					delete(a.ordered.cidKeys, cid)
+				}
+			}
+			a.ordered.Unlock()
+			return
+		}
+		msg := q.msgs[0]
+		q.msgs[0] = nil
+		q.msgs = q.msgs[1:]
+		a.ordered.Unlock()
+		session := a.newSession(msg)
+		session.ApplyChanges(q.changes[msg.Cid])
+		if c := a.dispatch(msg, session); c != nil {
+			q.changes[msg.Cid] = mergeChanges(q.changes[msg.Cid], c)
+		}
+	}
+}
+
+// mergeChanges 把后一次的修改合并到前面的修改上 同一个key以后一次为准
+func mergeChanges(dst, src *remote.SessionChanges) *remote.SessionChanges {
+	if dst == nil {
+		dst = &remote.SessionChanges{}
+	}
+	if src.Uid != "" {
+		dst.Uid = src.Uid
+	}
+	dst.Data, dst.Removed = mergeKeys(dst.Data, dst.Removed, src.Data, src.Removed)
+	dst.Servers, dst.UnboundServers = mergeKeys(dst.Servers, dst.UnboundServers, src.Servers, src.UnboundServers)
+	return dst
+}
+
+func mergeKeys[V any](set map[string]V, removed []string, srcSet map[string]V, srcRemoved []string) (map[string]V, []string) {
+	for k, v := range srcSet {
+		if set == nil {
+			set = make(map[string]V)
+		}
+		set[k] = v
+		removed = slices.DeleteFunc(removed, func(r string) bool { return r == k })
+	}
+	for _, k := range srcRemoved {
+		delete(set, k)
+		if !slices.Contains(removed, k) {
+			removed = append(removed, k)
+		}
+	}
+	return set, removed
+}
+
+func route(msg *remote.Msg) string {
+	if msg.Body == nil {
+		return ""
+	}
+	return msg.Body.Route
+}
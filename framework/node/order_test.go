@@ -0,0 +1,176 @@
+package node
+
+import (
+	"framework/myError"
+	"framework/net"
+	"framework/protocol"
+	"framework/remote"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type orderMsg struct {
+	cid   string
+	route string
+	name  string
+	data  map[string]any // connector发送时session中的数据
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name string
+		key  OrderKeyFunc
+		msgs []orderMsg
+		want []string // handler执行完成的顺序
+	}{
+		{
+			name: "same connection in order",
+			key:  OrderBySession,
+			msgs: []orderMsg{{"a", "slow", "a1", nil}, {"a", "fast", "a2", nil}, {"a", "fast", "a3", nil}},
+			want: []string{"a1", "a2", "a3"},
+		},
+		{
+			name: "different connections in parallel",
+			key:  OrderBySession,
+			msgs: []orderMsg{{"a", "slow", "a1", nil}, {"b", "fast", "b1", nil}},
+			want: []string{"b1", "a1"},
+		},
+		{
+			name: "no order key",
+			key:  func(*net.Session, string) string { return "" },
+			msgs: []orderMsg{{"a", "slow", "a1", nil}, {"a", "fast", "a2", nil}},
+			want: []string{"a2", "a1"},
+		},
+		{
+			name: "session changes carried to queued messages",
+			key:  OrderBySession,
+			msgs: []orderMsg{{"a", "join", "a1", nil}, {"a", "room", "a2", nil}},
+			want: []string{"a1", "a2:r1"},
+		},
+		{
+			name: "same room in order",
+			key:  OrderBySessionKey("roomId"),
+			msgs: []orderMsg{
+				{"a", "slow", "a1", map[string]any{"roomId": "r1"}},
+				{"b", "fast", "b1", map[string]any{"roomId": "r1"}},
+			},
+			want: []string{"a1", "b1"},
+		},
+		{
+			name: "key switch waits for the old queue",
+			key:  OrderBySessionKey("roomId"),
+			msgs: []orderMsg{
+				{"a", "slow", "a1", nil},
+				{"a", "fast", "a2", map[string]any{"roomId": "r1"}},
+				{"b", "fast", "b1", map[string]any{"roomId": "r1"}},
+			},
+			want: []string{"b1", "a1", "a2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, cli := newTestApp(len(tt.msgs))
+			a.SetOrderKey(tt.key)
+			var mu sync.Mutex
+			var done []string
+			finish := func(name string) (any, *myError.Error) {
+				mu.Lock()
+				defer mu.Unlock()
+				done = append(done, name)
+				return nil, nil
+			}
+			a.RegisterHandler(net.LogicHandler{
+				"slow": func(s *net.Session, body []byte) (any, *myError.Error) {
+					time.Sleep(50 * time.Millisecond)
+					return finish(string(body))
+				},
+				"fast": func(s *net.Session, body []byte) (any, *myError.Error) {
+					return finish(string(body))
+				},
+				"join": func(s *net.Session, body []byte) (any, *myError.Error) {
+					s.Put("roomId", "r1")
+					return finish(string(body))
+				},
+				"room": func(s *net.Session, body []byte) (any, *myError.Error) {
+					room, _ := s.Get("roomId")
+					return finish(string(body) + ":" + room.(string))
+				},
+			})
+			for _, m := range tt.msgs {
+				a.schedule(&remote.Msg{
+					Type:        remote.RequestMsg,
+					Cid:         m.cid,
+					Src:         "connector-test",
+					SessionData: m.data,
+					Body:        &protocol.Message{Type: protocol.Request, Route: m.route, Data: []byte(m.name)},
+				})
+			}
+			cli.wait(t, len(tt.msgs))
+			mu.Lock()
+			if !slices.Equal(done, tt.want) {
+				t.Fatalf("done = %v, want %v", done, tt.want)
+			}
+			mu.Unlock()
+			//回复之后队列才清理
+			deadline := time.Now().Add(time.Second)
+			for {
+				a.ordered.Lock()
+				queues, cidKeys := len(a.ordered.queues), len(a.ordered.cidKeys)
+				a.ordered.Unlock()
+				if queues == 0 && cidKeys == 0 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("queues = %d, cidKeys = %d after all messages", queues, cidKeys)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  *remote.SessionChanges
+		src  *remote.SessionChanges
+		want *remote.SessionChanges
+	}{
+		{
+			name: "first changes",
+			src:  &remote.SessionChanges{Uid: "u1", Data: map[string]any{"a": 1}},
+			want: &remote.SessionChanges{Uid: "u1", Data: map[string]any{"a": 1}},
+		},
+		{
+			name: "later value wins",
+			dst:  &remote.SessionChanges{Uid: "u1", Data: map[string]any{"a": 1}},
+			src:  &remote.SessionChanges{Data: map[string]any{"a": 2}},
+			want: &remote.SessionChanges{Uid: "u1", Data: map[string]any{"a": 2}},
+		},
+		{
+			name: "remove set key",
+			dst:  &remote.SessionChanges{Data: map[string]any{"a": 1}, Servers: map[string]string{"game": "g1"}},
+			src:  &remote.SessionChanges{Removed: []string{"a"}, UnboundServers: []string{"game"}},
+			want: &remote.SessionChanges{Data: map[string]any{}, Removed: []string{"a"}, Servers: map[string]string{}, UnboundServers: []string{"game"}},
+		},
+		{
+			name: "set removed key",
+			dst:  &remote.SessionChanges{Removed: []string{"a"}},
+			src:  &remote.SessionChanges{Data: map[string]any{"a": 3}},
+			want: &remote.SessionChanges{Data: map[string]any{"a": 3}, Removed: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeChanges(tt.dst, tt.src)
+			if got.Uid != tt.want.Uid ||
+				!maps.Equal(got.Data, tt.want.Data) || !slices.Equal(got.Removed, tt.want.Removed) ||
+				!maps.Equal(got.Servers, tt.want.Servers) || !slices.Equal(got.UnboundServers, tt.want.UnboundServers) {
+				t.Fatalf("mergeChanges = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package node
+
+import (
+	"common/logs"
+	"errors"
+	"framework/protocol"
+	"framework/remote"
+	"framework/serializer"
+	"sync/atomic"
+	"time"
+)
+
+var ErrPushTimeout = errors.New("push ack timeout")
+
+// track 记录用户和连接所在的connector 推送时按connector分组发送
+func (a *App) track(msg *remote.Msg) {
+	a.Lock()
+	defer a.Unlock()
+	if msg.Uid != "" {
+		a.users[msg.Uid] = msg.Src
+	}
+	if msg.Cid != "" {
+		a.cids[msg.Cid] = msg.Src
+	}
+}
+
+func (a *App) untrack(targets []string) {
+	a.Lock()
+	defer a.Unlock()
+	for _, v := range targets {
+		delete(a.users, v)
+		delete(a.cids, v)
+	}
+}
+
+// PushToUsers 通过用户所在的connector推送消息 返回不在线或找不到的uid
+func (a *App) PushToUsers(route string, data any, uids []string) ([]string, error) {
+	return a.push(route, data, uids, true)
+}
+
+// PushToCids 通过连接所在的connector推送消息 返回不在线或找不到的cid
+func (a *App) PushToCids(route string, data any, cids []string) ([]string, error) {
+	return a.push(route, data, cids, false)
+}
+
+func (a *App) push(route string, data any, targets []string, byUid bool) ([]string, error) {
+	var failed []string
+	groups := make(map[string][]string)
+	a.RLock()
+	for _, v := range targets {
+		connectorId, ok := a.cids[v]
+		if byUid {
+			connectorId, ok = a.users[v]
+		}
+		if !ok {
+			failed = append(failed, v)
+			continue
+		}
+		groups[connectorId] = append(groups[connectorId], v)
+	}
+	a.RUnlock()
+	res, err := a.pushGroups(route, data, groups, byUid)
+	return append(failed, res...), err
+}
+
+// PushToServers 按connector分组推送给用户 channel广播时使用
+func (a *App) PushToServers(route string, data any, uidsByServer map[string][]string) ([]string, error) {
+	return a.pushGroups(route, data, uidsByServer, true)
+}
+
+// pushGroups 给每个connector发一条推送 等待所有connector回复不在线的目标
+// 不知道目标连接协商的序列化方式 数据按每种方式都编码一次 由connector挑选
+func (a *App) pushGroups(route string, data any, groups map[string][]string, byUid bool) ([]string, error) {
+	var failed []string
+	bodies, err := serializer.MarshalAll(data)
+	if err != nil {
+		for _, list := range groups {
+			failed = append(failed, list...)
+		}
+		return failed, err
+	}
+	acks := make(map[string]pushWait, len(groups))
+	for connectorId, list := range groups {
+		seq := atomic.AddUint64(&a.seq, 1)
+		msg := &remote.Msg{
+			Type: remote.PushMsg,
+			Src:  a.serverId,
+			Dst:  connectorId,
+			Seq:  seq,
+			Body: &protocol.Message{
+				Type:  protocol.Push,
+				Route: route,
+			},
+			Bodies: bodies,
+		}
+		if byUid {
+			msg.Uids = list
+		} else {
+			msg.Cids = list
+		}
+		buf, err := msg.Encode()
+		if err == nil {
+			ch := a.wait(seq)
+			if err = a.remoteCli.SendMsg(connectorId, buf); err == nil {
+				acks[connectorId] = pushWait{seq: seq, ch: ch}
+				continue
+			}
+			a.done(seq)
+		}
+		logs.Error("push to connector[%s] err:%v", connectorId, err)
+		failed = append(failed, list...)
+	}
+
+	//等待所有connector的回复 超时的目标都算作失败
+	timeout := make(chan struct{})
+	timer := time.AfterFunc(a.rpcTimeout, func() {
+		close(timeout)
+	})
+	defer timer.Stop()
+	var pushErr error
+	for connectorId, w := range acks {
+		select {
+		case offline := <-w.ch:
+			a.untrack(offline)
+			failed = append(failed, offline...)
+		case <-timeout:
+			a.done(w.seq)
+			pushErr = ErrPushTimeout
+			failed = append(failed, groups[connectorId]...)
+		}
+	}
+	return failed, pushErr
+}
+
+type pushWait struct {
+	seq uint64
+	ch  chan []string
+}
+
+func (a *App) wait(seq uint64) chan []string {
+	ch := make(chan []string, 1)
+	a.Lock()
+	defer a.Unlock()
+	a.pending[seq] = ch
+	return ch
+}
+
+func (a *App) done(seq uint64) chan []string {
+	a.Lock()
+	defer a.Unlock()
+	ch := a.pending[seq]
+	delete(a.pending, seq)
+	return ch
+}
+
+// pushAck connector回复的推送结果
+func (a *App) pushAck(msg *remote.Msg) {
+	if ch := a.done(msg.Seq); ch != nil {
+		ch <- msg.Failed
+	}
+}
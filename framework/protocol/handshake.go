@@ -0,0 +1,79 @@
+package protocol
+
+const (
+	HandshakeOK             = 200 // 握手成功
+	HandshakeVersionTooLow  = 501 // 客户端版本过低 需要重新下载
+	HandshakeBadRequest     = 400 // 握手数据错误
+	DefaultSerializer       = "json"
+	DefaultHeartbeatSeconds = 3
+)
+
+// HandshakeBody 客户端发来的握手数据
+//
+//	{"sys":{"type":"js-websocket","version":"1.0.0","platform":"android","serializer":"json"},"user":{}}
+type HandshakeBody struct {
+	Sys  HandshakeClient `json:"sys"`
+	User map[string]any  `json:"user,omitempty"`
+}
+
+type HandshakeClient struct {
+	Type       string `json:"type"`       // 客户端类型 例如 js-websocket
+	Version    string `json:"version"`    // 客户端版本
+	Platform   string `json:"platform"`   // 平台 android ios web
+	Serializer string `json:"serializer"` // 期望的消息体序列化方式
+}
+
+// HandshakeResponse 服务端对握手包的回复
+type HandshakeResponse struct {
+	Code int          `json:"code"`
+	Sys  HandshakeSys `json:"sys"`
+}
+
+type HandshakeSys struct {
+	Heartbeat  int               `json:"heartbeat"`            // 心跳间隔 秒
+	Dict       map[string]uint16 `json:"dict,omitempty"`       // 路由压缩字典
+	Serializer string            `json:"serializer,omitempty"` // 协商后的序列化方式
+	ServerTime int64             `json:"serverTime"`           // 服务器时间 毫秒
+	// 断线后在宽限期内带上 ?resume=resumeToken 重新连接可以找回session 每次握手都会更换
+	ResumeToken string `json:"resumeToken,omitempty"`
+	Resumed     bool   `json:"resumed,omitempty"` // 是否恢复了之前的session
+}
+
+// 踢下线的原因
+const (
+	KickUnauthorized = 401 // 未认证就发送了其他消息 或者token无效
+	KickAuthTimeout  = 408 // 连接后长时间没有认证
+	KickDuplicated   = 409 // 同一个账号在其他地方登录
+	KickRateLimited  = 429 // 多次超过发送频率限制
+)
+
+// ReconnectRoute connector下线前推送 通知客户端重连到其他connector
+const ReconnectRoute = "sys.reconnect"
+
+// ReconnectBody 重连的目标 Host为空时客户端应该重新从gate获取connector
+type ReconnectBody struct {
+	Host   string `json:"host,omitempty"`
+	Port   int    `json:"port,omitempty"`
+	Secure bool   `json:"secure,omitempty"` // 是否使用wss
+	Reason string `json:"reason"`
+}
+
+// GameConfigRoute gameConfig中前端的配置变化后推送 内容和connector.entryHandler.config的返回相同 都是google.protobuf.Struct
+const GameConfigRoute = "sys.gameConfig"
+
+// WarningRoute 服务端警告客户端时推送的路由 例如发送过于频繁
+const WarningRoute = "sys.warning"
+
+// WarningBody 警告的内容 超过次数后会被踢下线
+type WarningBody struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+	Remain int    `json:"remain"` // 再被警告多少次会被踢下线
+}
+
+// KickBody 踢下线时发给客户端的原因
+type KickBody struct {
+	Code        int    `json:"code"`
+	Reason      string `json:"reason"`
+	DownloadUrl string `json:"downloadUrl,omitempty"`
+}
@@ -0,0 +1,248 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"strings"
+	"sync"
+)
+
+// MessageType 消息类型 只存在于Data包中
+type MessageType byte
+
+const (
+	Request  MessageType = 0x00 // 请求 需要回复 带id
+	Notify   MessageType = 0x01 // 通知 不需要回复
+	Response MessageType = 0x02 // 响应 带id 不带路由
+	Push     MessageType = 0x03 // 服务端主动推送 不带id
+)
+
+const (
+	msgRouteCompressMask = 0x01 // 路由是否压缩
+	msgTypeMask          = 0x07 // 消息类型 占3位
+	msgRouteLengthMask   = 0xFF // 未压缩路由最大长度
+	msgHeadLength        = 0x02
+	msgErrorMask         = 0x20 // 响应是否为错误
+)
+
+var (
+	ErrWrongMessageType  = errors.New("wrong message type")
+	ErrInvalidMessage    = errors.New("invalid message")
+	ErrRouteInfoNotFound = errors.New("route info not found in dictionary")
+	ErrRouteTooLong      = errors.New("route too long")
+)
+
+var (
+	routesMu sync.RWMutex
+	routes   = make(map[string]uint16) // 路由 -> 压缩码
+	codes    = make(map[uint16]string) // 压缩码 -> 路由
+)
+
+// Message 数据包中承载的消息
+// 格式：flag(1byte) | id(变长 request/response才有) | route(request/notify/push才有) | data
+// flag：preserved(2bit) | error(1bit) | preserved(1bit) | type(3bit) | routeCompressed(1bit)
+// route：压缩时为2字节的压缩码 未压缩时为1字节长度+路由字符串
+type Message struct {
+	Type            MessageType
+	ID              uint   // 请求和响应一一对应的消息id
+	Route           string // 路由 例如 hall.userHandler.info
+	Data            []byte
+	routeCompressed bool
+	Error           bool
+}
+
+func (t MessageType) String() string {
+	switch t {
+	case Request:
+		return "Request"
+	case Notify:
+		return "Notify"
+	case Response:
+		return "Response"
+	case Push:
+		return "Push"
+	}
+	return fmt.Sprintf("MessageType(%d)", byte(t))
+}
+
+func (m *Message) String() string {
+	return fmt.Sprintf("Type: %s, ID: %d, Route: %s, Compressed: %t, Error: %t, BodyLength: %d",
+		m.Type, m.ID, m.Route, m.routeCompressed, m.Error, len(m.Data))
+}
+
+func routable(t MessageType) bool {
+	return t == Request || t == Notify || t == Push
+}
+
+func invalidType(t MessageType) bool {
+	return t < Request || t > Push
+}
+
+// MessageEncode 按消息格式编码
+func MessageEncode(m *Message) ([]byte, error) {
+	if invalidType(m.Type) {
+		return nil, ErrWrongMessageType
+	}
+	buf := make([]byte, 0)
+	flag := byte(m.Type) << 1
+
+	routesMu.RLock()
+	code, compressed := routes[m.Route]
+	routesMu.RUnlock()
+	compressed = compressed && routable(m.Type)
+	if compressed {
+		flag |= msgRouteCompressMask
+	}
+	if m.Error {
+		flag |= msgErrorMask
+	}
+	buf = append(buf, flag)
+
+	if m.Type == Request || m.Type == Response {
+		n := m.ID
+		// variant length encode
+		for {
+			b := byte(n % 128)
+			n >>= 7
+			if n != 0 {
+				buf = append(buf, b+128)
+			} else {
+				buf = append(buf, b)
+				break
+			}
+		}
+	}
+
+	if routable(m.Type) {
+		if compressed {
+			buf = append(buf, byte((code>>8)&0xFF))
+			buf = append(buf, byte(code&0xFF))
+		} else {
+			if len(m.Route) > msgRouteLengthMask {
+				return nil, ErrRouteTooLong
+			}
+			buf = append(buf, byte(len(m.Route)))
+			buf = append(buf, []byte(m.Route)...)
+		}
+	}
+
+	buf = append(buf, m.Data...)
+	return buf, nil
+}
+
+// MessageDecode 解析Data包中的消息
+func MessageDecode(data []byte) (*Message, error) {
+	if len(data) < msgHeadLength {
+		return nil, ErrInvalidMessage
+	}
+	m := &Message{}
+	flag := data[0]
+	offset := 1
+	m.Type = MessageType((flag >> 1) & msgTypeMask)
+	if invalidType(m.Type) {
+		return nil, ErrWrongMessageType
+	}
+
+	if m.Type == Request || m.Type == Response {
+		// little end byte order variant length encode 最多10个字节 超过uint的算无效 32位平台上是uint32
+		id, n := binary.Uvarint(data[offset:])
+		if n <= 0 || id > math.MaxUint {
+			return nil, ErrInvalidMessage
+		}
+		m.ID = uint(id)
+		offset += n
+	}
+
+	m.Error = flag&msgErrorMask == msgErrorMask
+
+	if routable(m.Type) {
+		if flag&msgRouteCompressMask == 1 {
+			if offset+2 > len(data) {
+				return nil, ErrInvalidMessage
+			}
+			m.routeCompressed = true
+			code := binary.BigEndian.Uint16(data[offset:(offset + 2)])
+			routesMu.RLock()
+			route, ok := codes[code]
+			routesMu.RUnlock()
+			if !ok {
+				return nil, ErrRouteInfoNotFound
+			}
+			m.Route = route
+			offset += 2
+		} else {
+			if offset >= len(data) {
+				return nil, ErrInvalidMessage
+			}
+			m.routeCompressed = false
+			rl := int(data[offset])
+			offset++
+			if offset+rl > len(data) {
+				return nil, ErrInvalidMessage
+			}
+			m.Route = string(data[offset:(offset + rl)])
+			offset += rl
+		}
+	}
+
+	m.Data = data[offset:]
+	return m, nil
+}
+
+// SetDictionary 设置路由压缩字典 客户端握手时会拿到同一份字典
+// 路由或压缩码和已有的重复时返回错误 整个字典都不生效
+func SetDictionary(dict map[string]uint16) error {
+	return addRoutes(dict, false)
+}
+
+// AddDictionary 只补充字典中缺少的路由 已有的路由压缩码不同时返回错误
+// 路由字典是进程内共享的 同一个进程中可能有多个客户端或者connector
+func AddDictionary(dict map[string]uint16) error {
+	return addRoutes(dict, true)
+}
+
+// addRoutes 在拷贝上校验并添加 全部通过后才替换 出错时原来的字典不变
+// skipKnown为true时跳过已有并且压缩码相同的路由
+func addRoutes(dict map[string]uint16, skipKnown bool) error {
+	if len(dict) == 0 {
+		return nil
+	}
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	newRoutes := maps.Clone(routes)
+	newCodes := maps.Clone(codes)
+	for route, code := range dict {
+		r := strings.TrimSpace(route)
+		if old, ok := newRoutes[r]; ok {
+			if !skipKnown {
+				return fmt.Errorf("duplicated route(route: %s, code: %d)", r, code)
+			}
+			if old != code {
+				return fmt.Errorf("route %s already has code %d, got %d", r, old, code)
+			}
+			continue
+		}
+		if _, ok := newCodes[code]; ok {
+			return fmt.Errorf("duplicated route(route: %s, code: %d)", r, code)
+		}
+		newRoutes[r] = code
+		newCodes[code] = r
+	}
+	routes = newRoutes
+	codes = newCodes
+	return nil
+}
+
+// GetDictionary 获取路由压缩字典的拷贝
+func GetDictionary() map[string]uint16 {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	dict := make(map[string]uint16, len(routes))
+	for k, v := range routes {
+		dict[k] = v
+	}
+	return dict
+}
@@ -0,0 +1,171 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"maps"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// 测试用的压缩码 避免和其他测试或者配置中的字典冲突
+const (
+	testRoute     = "test.messageHandler.compressed"
+	testRouteCode = 0xFFF0
+	missingCode   = 0xFFF1
+)
+
+func init() {
+	if err := AddDictionary(map[string]uint16{testRoute: testRouteCode}); err != nil {
+		panic(err)
+	}
+}
+
+func TestMessageDecode(t *testing.T) {
+	//10个字节能表示uint64的最大值 32位平台上id是uint32 超过的算无效
+	tenByteId := &Message{Type: Response, ID: math.MaxUint, Data: []byte("x")}
+	var tenByteErr error
+	if strconv.IntSize == 32 {
+		tenByteId, tenByteErr = nil, ErrInvalidMessage
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want *Message
+		err  error
+	}{
+		{name: "too short", data: []byte{byte(Notify) << 1}, err: ErrInvalidMessage},
+		{name: "wrong type", data: []byte{0x04 << 1, 0x00}, err: ErrWrongMessageType},
+		{name: "unterminated id", data: []byte{byte(Response) << 1, 0x80, 0x80}, err: ErrInvalidMessage},
+		{
+			name: "10 byte id",
+			data: []byte{byte(Response) << 1, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01, 'x'},
+			want: tenByteId,
+			err:  tenByteErr,
+		},
+		{
+			name: "11 byte id",
+			data: []byte{byte(Response) << 1, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01},
+			err:  ErrInvalidMessage,
+		},
+		{
+			name: "id overflows uint64",
+			data: []byte{byte(Response) << 1, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x02},
+			err:  ErrInvalidMessage,
+		},
+		{name: "missing route length", data: []byte{byte(Request) << 1, 0x01}, err: ErrInvalidMessage},
+		{name: "route longer than data", data: []byte{byte(Notify) << 1, 0x05, 'a', 'b'}, err: ErrInvalidMessage},
+		{name: "truncated route code", data: []byte{byte(Push)<<1 | msgRouteCompressMask, 0xFF}, err: ErrInvalidMessage},
+		{name: "compressed route not in dictionary", data: []byte{byte(Push)<<1 | msgRouteCompressMask, 0xFF, 0xF1}, err: ErrRouteInfoNotFound},
+		{
+			name: "compressed route",
+			data: []byte{byte(Notify)<<1 | msgRouteCompressMask, 0xFF, 0xF0, '{', '}'},
+			want: &Message{Type: Notify, Route: testRoute, Data: []byte("{}"), routeCompressed: true},
+		},
+		{
+			name: "request",
+			data: []byte{byte(Request) << 1, 0xAC, 0x02, 0x03, 'a', '.', 'b', '{', '}'},
+			want: &Message{Type: Request, ID: 300, Route: "a.b", Data: []byte("{}")},
+		},
+		{
+			name: "error response",
+			data: []byte{byte(Response)<<1 | msgErrorMask, 0x01},
+			want: &Message{Type: Response, ID: 1, Data: []byte{}, Error: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := MessageDecode(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("MessageDecode() err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if !equalMessage(m, tt.want) {
+				t.Fatalf("MessageDecode() = %s, want %s", m, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *Message
+		want []byte
+		err  error
+	}{
+		{name: "wrong type", msg: &Message{Type: MessageType(0x04)}, err: ErrWrongMessageType},
+		{name: "route too long", msg: &Message{Type: Notify, Route: string(make([]byte, 256))}, err: ErrRouteTooLong},
+		{
+			name: "request",
+			msg:  &Message{Type: Request, ID: 300, Route: "a.b", Data: []byte("{}")},
+			want: []byte{byte(Request) << 1, 0xAC, 0x02, 0x03, 'a', '.', 'b', '{', '}'},
+		},
+		{
+			name: "max id",
+			msg:  &Message{Type: Response, ID: math.MaxUint},
+			want: binary.AppendUvarint([]byte{byte(Response) << 1}, math.MaxUint),
+		},
+		{
+			name: "compressed route",
+			msg:  &Message{Type: Push, Route: testRoute, Data: []byte("{}")},
+			want: []byte{byte(Push)<<1 | msgRouteCompressMask, 0xFF, 0xF0, '{', '}'},
+		},
+		{
+			name: "response never compresses",
+			msg:  &Message{Type: Response, ID: 1, Route: testRoute, Error: true},
+			want: []byte{byte(Response)<<1 | msgErrorMask, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := MessageEncode(tt.msg)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("MessageEncode() err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if !bytes.Equal(buf, tt.want) {
+				t.Fatalf("MessageEncode() = % x, want % x", buf, tt.want)
+			}
+		})
+	}
+}
+
+func TestDictionaryConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(map[string]uint16) error
+		dict map[string]uint16
+		err  bool
+	}{
+		{name: "add known route", set: AddDictionary, dict: map[string]uint16{testRoute: testRouteCode}},
+		{name: "set known route", set: SetDictionary, dict: map[string]uint16{testRoute: testRouteCode}, err: true},
+		{name: "add known route with another code", set: AddDictionary, dict: map[string]uint16{"test.a": 0xFFF2, testRoute: missingCode}, err: true},
+		{name: "add known code", set: AddDictionary, dict: map[string]uint16{"test.b": 0xFFF3, "test.c": testRouteCode}, err: true},
+		{name: "set duplicated code", set: SetDictionary, dict: map[string]uint16{"test.d": 0xFFF4, "test.e": 0xFFF4}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := GetDictionary()
+			err := tt.set(tt.dict)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %t", err, tt.err)
+			}
+			//失败时整个字典都不生效
+			if tt.err && !maps.Equal(before, GetDictionary()) {
+				t.Fatalf("dictionary changed after error: %v", GetDictionary())
+			}
+		})
+	}
+}
+
+func equalMessage(a, b *Message) bool {
+	return a.Type == b.Type && a.ID == b.ID && a.Route == b.Route && a.Error == b.Error &&
+		a.routeCompressed == b.routeCompressed && bytes.Equal(a.Data, b.Data)
+}
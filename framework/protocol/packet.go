@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PackageType 包类型 对应包头的第一个字节
+type PackageType byte
+
+const (
+	None         PackageType = 0x00
+	Handshake    PackageType = 0x01 // 握手 客户端 -> 服务端 服务端 -> 客户端
+	HandshakeAck PackageType = 0x02 // 握手确认 客户端 -> 服务端
+	Heartbeat    PackageType = 0x03 // 心跳 双向
+	Data         PackageType = 0x04 // 数据 双向
+	Kick         PackageType = 0x05 // 踢下线 服务端 -> 客户端
+)
+
+const (
+	HeaderLen     = 4         // 包头长度 1字节type + 3字节length
+	MaxPacketSize = 1<<24 - 1 // 3字节能表示的最大长度
+)
+
+var (
+	ErrPacketTooShort  = errors.New("packet too short")
+	ErrPacketTooLarge  = errors.New("packet too large")
+	ErrWrongPacketType = errors.New("wrong packet type")
+	ErrLengthMismatch  = errors.New("packet length mismatch")
+)
+
+// Packet 客户端和服务端之间传输的数据包
+// 格式：type(1byte) | length(3byte 大端) | body(length byte)
+type Packet struct {
+	Type PackageType
+	Len  uint32
+	Body []byte
+}
+
+func (p PackageType) String() string {
+	switch p {
+	case Handshake:
+		return "Handshake"
+	case HandshakeAck:
+		return "HandshakeAck"
+	case Heartbeat:
+		return "Heartbeat"
+	case Data:
+		return "Data"
+	case Kick:
+		return "Kick"
+	}
+	return fmt.Sprintf("PackageType(%d)", byte(p))
+}
+
+func (p PackageType) valid() bool {
+	return p >= Handshake && p <= Kick
+}
+
+// Decode 解析一个完整的数据包
+func Decode(payload []byte) (*Packet, error) {
+	if len(payload) < HeaderLen {
+		return nil, ErrPacketTooShort
+	}
+	typ := PackageType(payload[0])
+	if !typ.valid() {
+		return nil, ErrWrongPacketType
+	}
+	length := BytesToInt(payload[1:HeaderLen])
+	if length != len(payload)-HeaderLen {
+		return nil, ErrLengthMismatch
+	}
+	return &Packet{
+		Type: typ,
+		Len:  uint32(length),
+		Body: payload[HeaderLen:],
+	}, nil
+}
+
+// Encode 封装数据包 加上包头
+func Encode(typ PackageType, body []byte) ([]byte, error) {
+	if !typ.valid() {
+		return nil, ErrWrongPacketType
+	}
+	if len(body) > MaxPacketSize {
+		return nil, ErrPacketTooLarge
+	}
+	buf := make([]byte, HeaderLen+len(body))
+	buf[0] = byte(typ)
+	copy(buf[1:HeaderLen], IntToBytes(len(body)))
+	copy(buf[HeaderLen:], body)
+	return buf, nil
+}
+
+// BytesToInt 3字节大端转int
+func BytesToInt(b []byte) int {
+	result := 0
+	for _, v := range b {
+		result = result<<8 | int(v)
+	}
+	return result
+}
+
+// IntToBytes int转3字节大端
+func IntToBytes(n int) []byte {
+	buf := make([]byte, 3)
+	buf[0] = byte((n >> 16) & 0xFF)
+	buf[1] = byte((n >> 8) & 0xFF)
+	buf[2] = byte(n & 0xFF)
+	return buf
+}
@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPacketDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		typ     PackageType
+		body    []byte
+		err     error
+	}{
+		{name: "empty", payload: nil, err: ErrPacketTooShort},
+		{name: "truncated header", payload: []byte{byte(Data), 0x00, 0x00}, err: ErrPacketTooShort},
+		{name: "wrong type", payload: []byte{0x06, 0x00, 0x00, 0x00}, err: ErrWrongPacketType},
+		{name: "none type", payload: []byte{byte(None), 0x00, 0x00, 0x00}, err: ErrWrongPacketType},
+		{name: "body shorter than length", payload: []byte{byte(Data), 0x00, 0x00, 0x03, 'a', 'b'}, err: ErrLengthMismatch},
+		{name: "body longer than length", payload: []byte{byte(Data), 0x00, 0x00, 0x01, 'a', 'b'}, err: ErrLengthMismatch},
+		{name: "heartbeat without body", payload: []byte{byte(Heartbeat), 0x00, 0x00, 0x00}, typ: Heartbeat, body: []byte{}},
+		{name: "data", payload: []byte{byte(Data), 0x00, 0x00, 0x02, 'a', 'b'}, typ: Data, body: []byte("ab")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Decode(tt.payload)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Decode() err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if p.Type != tt.typ || int(p.Len) != len(tt.body) || !bytes.Equal(p.Body, tt.body) {
+				t.Fatalf("Decode() = %+v, want type %s body %q", p, tt.typ, tt.body)
+			}
+		})
+	}
+}
+
+func TestPacketEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  PackageType
+		body []byte
+		want []byte
+		err  error
+	}{
+		{name: "wrong type", typ: PackageType(0x06), err: ErrWrongPacketType},
+		{name: "too large", typ: Data, body: make([]byte, MaxPacketSize+1), err: ErrPacketTooLarge},
+		{name: "heartbeat", typ: Heartbeat, want: []byte{byte(Heartbeat), 0x00, 0x00, 0x00}},
+		{name: "three byte length", typ: Data, body: make([]byte, 0x010203), want: append([]byte{byte(Data), 0x01, 0x02, 0x03}, make([]byte, 0x010203)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := Encode(tt.typ, tt.body)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Encode() err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if !bytes.Equal(buf, tt.want) {
+				t.Fatalf("Encode() header = % x, want % x", buf[:HeaderLen], tt.want[:HeaderLen])
+			}
+			p, err := Decode(buf)
+			if err != nil {
+				t.Fatalf("Decode(Encode()) err = %v", err)
+			}
+			if p.Type != tt.typ || !bytes.Equal(p.Body, buf[HeaderLen:]) {
+				t.Fatalf("Decode(Encode()) = %s, want %s", p.Type, tt.typ)
+			}
+		})
+	}
+}
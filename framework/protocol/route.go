@@ -0,0 +1,37 @@
+package protocol
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidRoute = errors.New("invalid route")
+
+// Route 路由 格式为 serverType.handler.method 例如 hall.userHandler.info
+type Route struct {
+	ServerType string
+	Handler    string
+	Method     string
+}
+
+func (r *Route) String() string {
+	return r.ServerType + "." + r.Handler + "." + r.Method
+}
+
+// ParseRoute 解析路由字符串
+func ParseRoute(route string) (*Route, error) {
+	parts := strings.Split(route, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidRoute
+	}
+	for _, v := range parts {
+		if v == "" {
+			return nil, ErrInvalidRoute
+		}
+	}
+	return &Route{
+		ServerType: parts[0],
+		Handler:    parts[1],
+		Method:     parts[2],
+	}, nil
+}
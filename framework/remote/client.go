@@ -0,0 +1,24 @@
+package remote
+
+import (
+	"framework/game"
+	"strings"
+)
+
+// Client 服务器之间通信的客户端 每个服务器订阅以自己serverId为主题的消息
+type Client interface {
+	Run() error
+	Close() error
+	SendMsg(dst string, data []byte) error
+}
+
+const localScheme = "local://"
+
+// NewClient 根据servers.json中nats的配置创建客户端
+// url以local://开头时使用进程内的实现 方便在本地把connector hall game跑在同一个进程里调试
+func NewClient(serverId string, readChan chan []byte) Client {
+	if strings.HasPrefix(game.Conf.ServersConf().Nats.Url, localScheme) {
+		return NewLocalClient(serverId, readChan)
+	}
+	return NewNatsClient(serverId, readChan)
+}
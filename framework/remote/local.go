@@ -0,0 +1,59 @@
+package remote
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	localClients sync.Map // serverId -> *LocalClient
+
+	ErrServerNotFound = errors.New("remote server not found")
+	ErrClientClosed   = errors.New("remote client closed")
+)
+
+// LocalClient 进程内的实现 不依赖nats 消息直接投递到目标服务器的readChan
+type LocalClient struct {
+	serverId string
+	readChan chan []byte
+	closeCh  chan struct{}
+	once     sync.Once
+}
+
+func NewLocalClient(serverId string, readChan chan []byte) *LocalClient {
+	return &LocalClient{
+		serverId: serverId,
+		readChan: readChan,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+func (c *LocalClient) Run() error {
+	localClients.Store(c.serverId, c)
+	return nil
+}
+
+func (c *LocalClient) Close() error {
+	c.once.Do(func() {
+		localClients.Delete(c.serverId)
+		close(c.closeCh)
+	})
+	return nil
+}
+
+func (c *LocalClient) SendMsg(dst string, data []byte) error {
+	v, ok := localClients.Load(dst)
+	if !ok {
+		return ErrServerNotFound
+	}
+	target := v.(*LocalClient)
+	//和nats一样 接收方拿到的是一份拷贝
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	select {
+	case target.readChan <- buf:
+		return nil
+	case <-target.closeCh:
+		return ErrClientClosed
+	}
+}
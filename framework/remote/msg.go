@@ -0,0 +1,61 @@
+package remote
+
+import (
+	"encoding/json"
+	"framework/protocol"
+)
+
+// MsgType 服务器之间传递的消息类型
+type MsgType int
+
+const (
+	RequestMsg       MsgType = iota // connector转发客户端的request/notify给后端服务器
+	ResponseMsg                     // 后端服务器回复connector
+	SessionSyncMsg                  // 后端服务器只同步session 不需要回复客户端
+	PushMsg                         // 后端服务器通过connector推送消息给用户
+	PushAckMsg                      // connector回复push的结果 带上不在线的目标
+	SessionClosedMsg                // connector通知session绑定的后端服务器 连接已经断开
+	KickMsg                         // 通知其他connector踢掉某个连接 例如重复登录
+)
+
+// Msg 服务器之间传递的消息 每条消息都带上session的数据
+type Msg struct {
+	Type        MsgType            `json:"type"`
+	Cid         string             `json:"cid"`
+	Uid         string             `json:"uid"`
+	Src         string             `json:"src"` // 发送方serverId 回复时作为目标
+	Dst         string             `json:"dst"`
+	Body        *protocol.Message  `json:"body"`
+	SessionData map[string]any     `json:"sessionData"`
+	Servers     map[string]string  `json:"servers"`
+	Uids        []string           `json:"uids,omitempty"`       // push的目标用户
+	Cids        []string           `json:"cids,omitempty"`       // push的目标连接
+	Seq         uint64             `json:"seq,omitempty"`        // push和ack一一对应
+	Failed      []string           `json:"failed,omitempty"`     // 不在线或找不到的目标
+	Kick        *protocol.KickBody `json:"kick,omitempty"`       // 踢人的原因
+	Serializer  string             `json:"serializer,omitempty"` // 客户端协商的序列化方式 后端按它编解码消息体
+	Bodies      map[string][]byte  `json:"bodies,omitempty"`     // push按每种序列化方式编码好的消息体
+	Changes     *SessionChanges    `json:"changes,omitempty"`    // 后端服务器回复时只带session变化的部分
+}
+
+// SessionChanges handler对session的修改 connector按key合并
+// 同一个session绑定了多台服务器或者有多个请求在处理时 不会互相覆盖
+type SessionChanges struct {
+	Uid            string            `json:"uid,omitempty"`
+	Data           map[string]any    `json:"data,omitempty"`
+	Removed        []string          `json:"removed,omitempty"`
+	Servers        map[string]string `json:"servers,omitempty"`
+	UnboundServers []string          `json:"unboundServers,omitempty"`
+}
+
+func (m *Msg) Encode() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func DecodeMsg(data []byte) (*Msg, error) {
+	var m Msg
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
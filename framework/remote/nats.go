@@ -0,0 +1,67 @@
+package remote
+
+import (
+	"common/logs"
+	"framework/game"
+	"github.com/nats-io/nats.go"
+)
+
+type NatsClient struct {
+	serverId string
+	conn     *nats.Conn
+	sub      *nats.Subscription
+	readChan chan []byte
+}
+
+func NewNatsClient(serverId string, readChan chan []byte) *NatsClient {
+	return &NatsClient{
+		serverId: serverId,
+		readChan: readChan,
+	}
+}
+
+func (c *NatsClient) Run() error {
+	var err error
+	c.conn, err = nats.Connect(game.Conf.ServersConf().Nats.Url)
+	if err != nil {
+		logs.Error("connect nats server fail,err:%v", err)
+		return err
+	}
+	if err := c.subscribe(); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (c *NatsClient) Close() error {
+	if c.sub != nil {
+		if err := c.sub.Unsubscribe(); err != nil {
+			logs.Error("nats unsubscribe err:%v", err)
+		}
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// subscribe 订阅自己serverId的主题 收到的消息交给readChan处理
+func (c *NatsClient) subscribe() error {
+	var err error
+	c.sub, err = c.conn.Subscribe(c.serverId, func(msg *nats.Msg) {
+		c.readChan <- msg.Data
+	})
+	if err != nil {
+		logs.Error("nats subscribe err:%v", err)
+	}
+	return err
+}
+
+func (c *NatsClient) SendMsg(dst string, data []byte) error {
+	if c.conn == nil {
+		return ErrClientClosed
+	}
+	return c.conn.Publish(dst, data)
+}
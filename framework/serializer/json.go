@@ -0,0 +1,34 @@
+package serializer
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// JsonSerializer H5调试客户端使用 proto生成的类型按protojson的规则编码 零值字段也会输出
+type JsonSerializer struct{}
+
+var (
+	protojsonMarshal   = protojson.MarshalOptions{EmitUnpopulated: true}
+	protojsonUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+func (JsonSerializer) Name() string {
+	return Json
+}
+
+func (JsonSerializer) Marshal(v any) ([]byte, error) {
+	if m, ok := v.(proto.Message); ok {
+		return protojsonMarshal.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+func (JsonSerializer) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return protojsonUnmarshal.Unmarshal(data, m)
+	}
+	return json.Unmarshal(data, v)
+}
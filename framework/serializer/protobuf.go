@@ -0,0 +1,28 @@
+package serializer
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufSerializer 正式环境使用 消息体必须是.proto生成的类型
+type ProtobufSerializer struct{}
+
+func (ProtobufSerializer) Name() string {
+	return Protobuf
+}
+
+func (ProtobufSerializer) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtobufSerializer) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,81 @@
+package serializer
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+const (
+	Json     = "json"
+	Protobuf = "protobuf"
+)
+
+var ErrNotProtoMessage = errors.New("serializer: value is not a proto.Message")
+
+// Serializer 消息体的序列化方式 握手时按连接协商 request/response/push的消息体都使用它编解码
+type Serializer interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	mu          sync.RWMutex
+	serializers = map[string]Serializer{
+		Json:     JsonSerializer{},
+		Protobuf: ProtobufSerializer{},
+	}
+)
+
+// Register 注册自定义的序列化方式 同名的会被覆盖
+func Register(s Serializer) {
+	mu.Lock()
+	defer mu.Unlock()
+	serializers[s.Name()] = s
+}
+
+func Get(name string) (Serializer, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := serializers[name]
+	return s, ok
+}
+
+// Default 没有协商或者协商失败时使用json
+func Default() Serializer {
+	return JsonSerializer{}
+}
+
+// Names 所有注册的序列化方式
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(serializers))
+	for name := range serializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MarshalAll 用每一种序列化方式编码 推送时后端不知道目标连接用的哪一种
+// 不支持的方式直接跳过 例如非proto.Message不能用protobuf编码 全部失败时返回最后一个错误
+func MarshalAll(v any) (map[string][]byte, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	bodies := make(map[string][]byte, len(serializers))
+	var err error
+	for name, s := range serializers {
+		data, e := s.Marshal(v)
+		if e != nil {
+			err = e
+			continue
+		}
+		bodies[name] = data
+	}
+	if len(bodies) == 0 {
+		return nil, err
+	}
+	return bodies, nil
+}
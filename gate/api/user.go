@@ -4,6 +4,7 @@ import (
 	"common"
 	"common/biz"
 	"common/config"
+	"common/discovery"
 	"common/jwts"
 	"common/logs"
 	"common/rpc"
@@ -11,16 +12,23 @@ import (
 	"framework/myError"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"net"
+	"strconv"
 	"time"
 	"user/pb"
 )
 
 type UserHandler struct {
+	connectors *discovery.ServerList //etcd中注册的connector 为空时使用配置文件中的connector
+	balance    discovery.Balance
 }
 
 // NewUserHandler 返回值类型：*UserHandler;创建 UserHandler 的零值实例，并取其地址：&UserHandler{}
-func NewUserHandler() *UserHandler {
-	return &UserHandler{}
+func NewUserHandler(connectors *discovery.ServerList, balance discovery.Balance) *UserHandler {
+	return &UserHandler{
+		connectors: connectors,
+		balance:    balance,
+	}
 }
 
 // Register 注册的逻辑业务
@@ -64,12 +72,30 @@ func (u *UserHandler) Register(ctx *gin.Context) {
 	result := map[string]any{
 		//	返回一个token。token使用jwt来生成
 		//JWT由三部分组成，1、头，定义加密算法2、存储数据3、签名（base64）
-		"token": token,
-		"serverInfo": map[string]any{
-			"host": config.Conf.Services["connector"].ClientHost,
-			"port": config.Conf.Services["connector"].ClientPort,
-		},
+		"token":      token,
+		"serverInfo": u.selectConnector(uid),
 	}
 
 	common.Success(ctx, result)
 }
+
+// selectConnector 从etcd注册的connector中按策略选择一个 没有时使用配置文件中的
+func (u *UserHandler) selectConnector(uid string) map[string]any {
+	if u.connectors != nil {
+		if server, ok := u.connectors.Pick(u.balance, uid); ok {
+			host, port, err := net.SplitHostPort(server.Addr)
+			if err == nil {
+				p, _ := strconv.Atoi(port)
+				return map[string]any{
+					"host": host,
+					"port": p,
+				}
+			}
+			logs.Error("invalid connector addr:%s, err:%v", server.Addr, err)
+		}
+	}
+	return map[string]any{
+		"host": config.Conf.Services["connector"].ClientHost,
+		"port": config.Conf.Services["connector"].ClientPort,
+	}
+}
@@ -2,6 +2,8 @@ package router
 
 import (
 	"common/config"
+	"common/discovery"
+	"common/logs"
 	"common/rpc"
 	"gate/api"
 	"gate/auth"
@@ -20,8 +22,26 @@ func RegisterRouter() *gin.Engine {
 	rpc.Init()
 	r := gin.Default()
 	r.Use(auth.Cors())
-	userHandler := api.NewUserHandler()
+	userHandler := api.NewUserHandler(connectors())
 	r.POST("/register", userHandler.Register)
 	return r
 
 }
+
+// connectors 监听etcd中注册的connector 没有配置时返回nil 使用services中的connector
+func connectors() (*discovery.ServerList, discovery.Balance) {
+	domain, ok := config.Conf.Domain["connector"]
+	if !ok || domain.Name == "" {
+		return nil, ""
+	}
+	list, err := discovery.NewServerList(config.Conf.Etcd, domain.Name)
+	if err != nil {
+		logs.Error("watch connectors from etcd err:%v", err)
+		return nil, ""
+	}
+	balance := discovery.Balance(domain.Balance)
+	if balance == "" {
+		balance = discovery.BalanceLoad
+	}
+	return list, balance
+}
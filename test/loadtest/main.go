@@ -0,0 +1,97 @@
+// loadtest 模拟大量websocket玩家压测 每个玩家通过gate注册 连接返回的connector后按场景执行
+//
+//	go run ./loadtest -gate http://127.0.0.1:13000 -n 2000 -rate 200 -duration 1m
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config 命令行参数
+type Config struct {
+	Gate          string
+	Players       int
+	Rate          int           //每秒连接的玩家数
+	Duration      time.Duration //准备后执行随机动作的时间
+	Interval      time.Duration //动作的平均间隔
+	Timeout       time.Duration //请求超时
+	Secure        bool          //connector使用wss
+	AccountPrefix string
+	Password      string
+	Scenario      string
+}
+
+func parseFlags() *Config {
+	conf := &Config{}
+	flag.StringVar(&conf.Gate, "gate", "http://127.0.0.1:13000", "gate的http地址")
+	flag.IntVar(&conf.Players, "n", 100, "模拟的玩家数")
+	flag.IntVar(&conf.Rate, "rate", 50, "每秒连接的玩家数")
+	flag.DurationVar(&conf.Duration, "duration", 30*time.Second, "准备后执行随机动作的时间")
+	flag.DurationVar(&conf.Interval, "interval", time.Second, "动作的平均间隔")
+	flag.DurationVar(&conf.Timeout, "timeout", 5*time.Second, "请求超时")
+	flag.BoolVar(&conf.Secure, "secure", false, "使用wss连接connector")
+	flag.StringVar(&conf.AccountPrefix, "account", fmt.Sprintf("bot%d_", time.Now().Unix()), "注册账号的前缀 后面拼上玩家序号")
+	flag.StringVar(&conf.Password, "password", "123456", "注册账号的密码")
+	flag.StringVar(&conf.Scenario, "scenario", "", "场景的json文件 为空时使用默认场景")
+	flag.Parse()
+	conf.Rate = max(conf.Rate, 1)
+	conf.Interval = max(conf.Interval, time.Millisecond)
+	return conf
+}
+
+func main() {
+	conf := parseFlags()
+	scenario, err := loadScenario(conf.Scenario)
+	if err != nil {
+		log.Fatalf("load scenario err:%v", err)
+	}
+	stats := NewStats(conf.Players)
+	httpCli := &http.Client{
+		Timeout: conf.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: conf.Rate,
+		},
+	}
+	//Ctrl+C提前结束 同样输出报告
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	log.Printf("start %d players, rate=%d/s, gate=%s", conf.Players, conf.Rate, conf.Gate)
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Second / time.Duration(conf.Rate))
+	defer ticker.Stop()
+	//所有玩家都连上以后再执行duration
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+ramp:
+	for i := 0; i < conf.Players; i++ {
+		select {
+		case <-ctx.Done():
+			break ramp
+		case <-ticker.C:
+		}
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			NewPlayer(id, conf, scenario, stats, httpCli).Run(runCtx)
+		}(i)
+	}
+	log.Printf("all players started in %s, run %s", time.Since(start).Round(time.Millisecond), conf.Duration)
+	select {
+	case <-ctx.Done():
+	case <-time.After(conf.Duration):
+	}
+	cancel()
+	wg.Wait()
+	stats.Report(os.Stdout, time.Since(start))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"common/biz"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"framework/client"
+	"framework/myError"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 注册和连接也按路由统计延迟
+const (
+	registerRoute = "gate /register"
+	connectRoute  = "connector handshake"
+)
+
+// registerResult gate的返回格式 成功时msg是token和connector地址 失败时msg是错误信息
+type registerResult struct {
+	Code int             `json:"code"`
+	Msg  json.RawMessage `json:"msg"`
+}
+
+type registerData struct {
+	Token      string `json:"token"`
+	ServerInfo struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	} `json:"serverInfo"`
+}
+
+// Player 模拟的玩家 注册后连接gate返回的connector 按场景执行
+type Player struct {
+	id       int
+	conf     *Config
+	scenario *Scenario
+	stats    *Stats
+	http     *http.Client
+}
+
+func NewPlayer(id int, conf *Config, scenario *Scenario, stats *Stats, httpCli *http.Client) *Player {
+	return &Player{
+		id:       id,
+		conf:     conf,
+		scenario: scenario,
+		stats:    stats,
+		http:     httpCli,
+	}
+}
+
+// Run 任何一个阶段失败都直接退出 ctx结束后断开连接
+func (p *Player) Run(ctx context.Context) {
+	start := time.Now()
+	data, err := p.register()
+	p.stats.request(registerRoute, time.Since(start), err)
+	if err != nil {
+		return
+	}
+	p.stats.stage(stageRegister)
+
+	c := client.NewClient(net.JoinHostPort(data.ServerInfo.Host, strconv.Itoa(data.ServerInfo.Port)))
+	c.Secure = p.conf.Secure
+	c.RequestTimeout = p.conf.Timeout
+	start = time.Now()
+	err = c.Connect()
+	p.stats.request(connectRoute, time.Since(start), err)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	p.stats.stage(stageConnect)
+	c.OnClose(func(err error) {
+		if ctx.Err() == nil && !errors.Is(err, client.ErrClosed) {
+			p.stats.disconnected()
+			p.stats.fail(err)
+		}
+	})
+	for _, route := range p.scenario.Pushes {
+		c.On(route, func([]byte) {
+			p.stats.push(route)
+		})
+	}
+
+	start = time.Now()
+	_, err = c.Auth(data.Token)
+	p.stats.request(c.AuthRoute, time.Since(start), err)
+	if err != nil {
+		return
+	}
+	p.stats.stage(stageAuth)
+	if !p.step(c, p.scenario.Join) {
+		return
+	}
+	p.stats.stage(stageJoin)
+	if !p.step(c, p.scenario.Ready) {
+		return
+	}
+	p.stats.stage(stageReady)
+
+	for {
+		//动作之间随机间隔 避免所有玩家同时发送
+		wait := p.conf.Interval/2 + rand.N(p.conf.Interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.Done():
+			return
+		case <-time.After(wait):
+		}
+		if action, ok := p.scenario.randomAction(); ok {
+			p.step(c, action)
+		}
+	}
+}
+
+// step 执行一步 路由为空的跳过
+func (p *Player) step(c *client.Client, s Step) bool {
+	if s.Route == "" {
+		return true
+	}
+	body := s.Body
+	if len(body) == 0 {
+		body = json.RawMessage(`{}`)
+	}
+	if s.Notify {
+		if err := c.Notify(s.Route, body); err != nil {
+			p.stats.fail(err)
+			return false
+		}
+		return true
+	}
+	start := time.Now()
+	var res json.RawMessage
+	err := c.Request(s.Route, body, &res)
+	p.stats.request(s.Route, time.Since(start), err)
+	return err == nil
+}
+
+// register 每个玩家用不同的账号注册 拿到token和connector地址
+func (p *Player) register() (*registerData, error) {
+	body, _ := json.Marshal(map[string]any{
+		"account":       fmt.Sprintf("%s%d", p.conf.AccountPrefix, p.id),
+		"password":      p.conf.Password,
+		"loginPlatform": 1,
+	})
+	resp, err := p.http.Post(p.conf.Gate+"/register", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gate register http status %d", resp.StatusCode)
+	}
+	var result registerResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Code != biz.OK {
+		var msg string
+		_ = json.Unmarshal(result.Msg, &msg)
+		return nil, myError.NewError(result.Code, errors.New(msg))
+	}
+	var data registerData
+	if err := json.Unmarshal(result.Msg, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"framework/protocol"
+	"math/rand/v2"
+	"os"
+)
+
+// Step 场景中的一步 消息体是json 按权重随机选择动作
+type Step struct {
+	Route  string          `json:"route"`
+	Body   json.RawMessage `json:"body"`
+	Notify bool            `json:"notify"` //通知不等待回复 不统计延迟
+	Weight int             `json:"weight"`
+}
+
+// Scenario 每个玩家认证后依次执行 加入房间 准备 然后在持续时间内随机执行动作
+type Scenario struct {
+	Join    Step     `json:"join"`
+	Ready   Step     `json:"ready"`
+	Actions []Step   `json:"actions"`
+	Pushes  []string `json:"pushes"` //统计收到的推送数 只能按路由订阅
+}
+
+// defaultScenario 路由需要和部署的game服务器一致 可以用-scenario指定json文件
+func defaultScenario() *Scenario {
+	return &Scenario{
+		Join:  Step{Route: "game.roomHandler.join", Body: json.RawMessage(`{}`)},
+		Ready: Step{Route: "game.roomHandler.ready", Body: json.RawMessage(`{}`)},
+		Actions: []Step{
+			{Route: "game.roomHandler.chat", Body: json.RawMessage(`{"msg":"hello"}`), Weight: 1},
+			{Route: "game.roomHandler.action", Body: json.RawMessage(`{}`), Weight: 3},
+		},
+		Pushes: []string{protocol.WarningRoute, protocol.ReconnectRoute},
+	}
+}
+
+func loadScenario(file string) (*Scenario, error) {
+	if file == "" {
+		return defaultScenario(), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := defaultScenario()
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// randomAction 按权重选择一个动作 权重为0的按1计算
+func (s *Scenario) randomAction() (Step, bool) {
+	if len(s.Actions) == 0 {
+		return Step{}, false
+	}
+	total := 0
+	for _, a := range s.Actions {
+		total += max(a.Weight, 1)
+	}
+	n := rand.IntN(total)
+	for _, a := range s.Actions {
+		n -= max(a.Weight, 1)
+		if n < 0 {
+			return a, true
+		}
+	}
+	return s.Actions[len(s.Actions)-1], true
+}
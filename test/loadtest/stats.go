@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"framework/myError"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 玩家上线的几个阶段 用来统计连接的成功率
+const (
+	stageRegister = "register"
+	stageConnect  = "connect"
+	stageAuth     = "auth"
+	stageJoin     = "join"
+	stageReady    = "ready"
+)
+
+var stages = []string{stageRegister, stageConnect, stageAuth, stageJoin, stageReady}
+
+type routeStats struct {
+	latencies []time.Duration
+	errors    int
+}
+
+type codeStats struct {
+	msg   string
+	count int
+}
+
+// Stats 所有玩家共用 压测结束后输出报告
+type Stats struct {
+	sync.Mutex
+	players    int
+	stages     map[string]int // 阶段 -> 成功的玩家数
+	routes     map[string]*routeStats
+	codes      map[int]*codeStats // common/biz中的错误码
+	clientErrs map[string]int     // 没有拿到服务端回复的错误 例如超时 连接断开
+	pushes     map[string]int
+	disconnect int // 场景结束前断开的连接
+}
+
+func NewStats(players int) *Stats {
+	return &Stats{
+		players:    players,
+		stages:     make(map[string]int),
+		routes:     make(map[string]*routeStats),
+		codes:      make(map[int]*codeStats),
+		clientErrs: make(map[string]int),
+		pushes:     make(map[string]int),
+	}
+}
+
+func (s *Stats) stage(name string) {
+	s.Lock()
+	defer s.Unlock()
+	s.stages[name]++
+}
+
+// request 记录一次请求 有服务端错误码的按错误码统计 其他的按错误信息统计
+func (s *Stats) request(route string, cost time.Duration, err error) {
+	s.Lock()
+	defer s.Unlock()
+	rs, ok := s.routes[route]
+	if !ok {
+		rs = &routeStats{}
+		s.routes[route] = rs
+	}
+	//服务端回复了错误码的也是一次完整的往返 一起统计延迟
+	var e *myError.Error
+	if err == nil || errors.As(err, &e) {
+		rs.latencies = append(rs.latencies, cost)
+	}
+	if err != nil {
+		rs.errors++
+		s.error(err)
+	}
+}
+
+func (s *Stats) fail(err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.error(err)
+}
+
+func (s *Stats) error(err error) {
+	var e *myError.Error
+	if errors.As(err, &e) {
+		cs, ok := s.codes[e.Code]
+		if !ok {
+			cs = &codeStats{msg: e.Error()}
+			s.codes[e.Code] = cs
+		}
+		cs.count++
+		return
+	}
+	s.clientErrs[err.Error()]++
+}
+
+func (s *Stats) push(route string) {
+	s.Lock()
+	defer s.Unlock()
+	s.pushes[route]++
+}
+
+func (s *Stats) disconnected() {
+	s.Lock()
+	defer s.Unlock()
+	s.disconnect++
+}
+
+// Report 连接成功率 每个路由的延迟分位数 错误码和推送数
+func (s *Stats) Report(w io.Writer, elapsed time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	fmt.Fprintf(w, "\n==== players=%d elapsed=%s ====\n", s.players, elapsed.Round(time.Millisecond))
+	fmt.Fprintln(w, "\n-- stages --")
+	for _, name := range stages {
+		ok := s.stages[name]
+		fmt.Fprintf(w, "%-10s %6d/%-6d %6.2f%%\n", name, ok, s.players, percent(ok, s.players))
+	}
+	fmt.Fprintf(w, "%-10s %6d\n", "dropped", s.disconnect)
+
+	fmt.Fprintln(w, "\n-- routes --")
+	fmt.Fprintf(w, "%-40s %8s %6s %10s %10s %10s %10s %10s\n", "route", "resp", "err", "avg", "p50", "p90", "p99", "max")
+	for _, route := range sortedKeys(s.routes) {
+		rs := s.routes[route]
+		l := rs.latencies
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		fmt.Fprintf(w, "%-40s %8d %6d %10s %10s %10s %10s %10s\n", route, len(l), rs.errors,
+			fmtDuration(avg(l)), fmtDuration(quantile(l, 0.5)), fmtDuration(quantile(l, 0.9)),
+			fmtDuration(quantile(l, 0.99)), fmtDuration(quantile(l, 1)))
+	}
+
+	if len(s.codes) > 0 {
+		fmt.Fprintln(w, "\n-- error codes --")
+		codes := make([]int, 0, len(s.codes))
+		for code := range s.codes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "%6d %-20s %8d\n", code, s.codes[code].msg, s.codes[code].count)
+		}
+	}
+	if len(s.clientErrs) > 0 {
+		fmt.Fprintln(w, "\n-- client errors --")
+		for _, msg := range sortedKeys(s.clientErrs) {
+			fmt.Fprintf(w, "%8d %s\n", s.clientErrs[msg], msg)
+		}
+	}
+	if len(s.pushes) > 0 {
+		fmt.Fprintln(w, "\n-- pushes --")
+		for _, route := range sortedKeys(s.pushes) {
+			fmt.Fprintf(w, "%-40s %8d\n", route, s.pushes[route])
+		}
+	}
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+func avg(l []time.Duration) time.Duration {
+	if len(l) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range l {
+		sum += d
+	}
+	return sum / time.Duration(len(l))
+}
+
+// quantile l需要已经排好序
+func quantile(l []time.Duration, q float64) time.Duration {
+	if len(l) == 0 {
+		return 0
+	}
+	i := int(float64(len(l))*q+0.5) - 1
+	return l[min(max(i, 0), len(l)-1)]
+}
+
+func fmtDuration(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}